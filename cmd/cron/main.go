@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"net/url"
 	"os"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/batch"
 	"github.com/aws/aws-sdk-go/service/batch/batchiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	"github.com/robfig/cron"
@@ -18,16 +23,30 @@ import (
 	"github.com/ReconfigureIO/platform/config"
 	"github.com/ReconfigureIO/platform/handlers/api"
 	"github.com/ReconfigureIO/platform/models"
+	batchreco "github.com/ReconfigureIO/platform/service/batch"
+	"github.com/ReconfigureIO/platform/service/batchretry"
 	"github.com/ReconfigureIO/platform/service/billing_hours"
 	"github.com/ReconfigureIO/platform/service/cw_id_watcher"
 	"github.com/ReconfigureIO/platform/service/deployment"
 	"github.com/ReconfigureIO/platform/service/fpgaimage/afi"
 	"github.com/ReconfigureIO/platform/service/fpgaimage/afi/afiwatcher"
+	"github.com/ReconfigureIO/platform/service/queue"
+	"github.com/ReconfigureIO/platform/service/retention"
+	"github.com/ReconfigureIO/platform/service/storage"
+	s3reco "github.com/ReconfigureIO/platform/service/storage/s3"
+	"github.com/ReconfigureIO/platform/service/webhooks"
 )
 
 var (
 	deploy          deployment.Service
 	awsBatchService batchiface.BatchAPI
+	recoBatch       batchreco.Service
+	batchBackend    string
+	retryConfig     batchretry.Config
+	storageService  storage.Service
+	retentionConfig retention.Config
+	apiBaseURL      url.URL
+	depQueue        bool
 
 	db *gorm.DB
 
@@ -52,10 +71,35 @@ func setup(*cobra.Command, []string) {
 		log.Fatal(err)
 	}
 
-	deploy = deployment.New(conf.Reco.Deploy)
+	deploy, err = deployment.NewFromConfig(conf.Reco.Deploy)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	sess := session.New()
 	awsBatchService = batch.New(sess)
+	recoBatch, err = batchreco.NewFromConfig(conf.Reco.Batch, conf.Reco.AWS)
+	if err != nil {
+		log.Fatal(err)
+	}
+	batchBackend = conf.Reco.Batch.Backend
+	retryConfig = conf.Reco.Retry
+	depQueue = conf.Reco.FeatureDepQueue
+
+	storageSession := session.New(&aws.Config{
+		Endpoint: aws.String(os.Getenv("S3_ENDPOINT")),
+	})
+	storageService = &s3reco.Service{
+		Bucket:      conf.Reco.StorageBucket,
+		UploaderAPI: s3manager.NewUploader(storageSession),
+		S3API:       s3.New(storageSession),
+	}
+	retentionConfig = conf.Reco.Retention
+
+	apiBaseURL = url.URL{Host: conf.Host, Scheme: "https"}
+	if conf.Reco.Env == "development-on-prem" {
+		apiBaseURL.Scheme = "http"
+	}
 
 	db = config.SetupDB(conf)
 	api.DB(db)
@@ -102,10 +146,18 @@ func cronCmd() {
 	}
 
 	schedule(5*time.Minute, generatedAFIs)
-	schedule(5*time.Minute, getBatchJobLogNames)
+	// logs of jobs on other backends are found by their job IDs
+	if batchBackend == batchreco.BackendAWSBatch {
+		schedule(5*time.Minute, getBatchJobLogNames)
+	}
+	schedule(time.Minute, retryFailedBatchJobs)
 	schedule(time.Minute, terminateDeployments)
 	schedule(time.Minute, checkHours)
 	schedule(time.Minute, findDeploymentIPs)
+	schedule(time.Minute, startScheduledDeployments)
+	schedule(time.Minute, stopExpiredDeployments)
+	schedule(30*time.Second, deliverWebhooks)
+	schedule(24*time.Hour, collectGarbage)
 
 	worker.Start()
 	log.Printf("starting workers")
@@ -138,6 +190,108 @@ func findDeploymentIPs() {
 	}
 }
 
+func startScheduledDeployments() {
+	log.Printf("starting scheduled deployments")
+	d := models.DeploymentDataSource(db)
+	ctx := context.Background()
+
+	start := deployment.ScheduledStart{
+		CallbackURL: func(dep models.Deployment) string {
+			u := apiBaseURL
+			u.Path = "/deployments/" + dep.ID + "/events"
+			u.RawQuery = fmt.Sprintf("token=%s", dep.Token)
+			return u.String()
+		},
+		HasHours: func(dep models.Deployment) (bool, error) {
+			user := models.User{}
+			err := db.First(&user, "id = ?", dep.UserID).Error
+			if err != nil {
+				return false, err
+			}
+			sub, err := models.SubscriptionDataSource(db).CurrentSubscription(user)
+			if err != nil {
+				return false, err
+			}
+			usedHours, err := models.DeploymentHoursBtw(d, user.ID, sub.StartTime, sub.EndTime)
+			if err != nil {
+				return false, err
+			}
+			return usedHours < sub.Hours, nil
+		},
+	}
+	if depQueue {
+		start.Push = func(dep models.Deployment) error {
+			user := models.User{}
+			err := db.First(&user, "id = ?", dep.UserID).Error
+			if err != nil {
+				return err
+			}
+			plan := models.PlanOpenSource
+			sub, err := models.SubscriptionDataSource(db).CurrentSubscription(user)
+			if err == nil {
+				plan = sub.Identifier
+			}
+			q := queue.NewQueueService(db)
+			return q.Push(queue.TypeDeployment, queue.Job{
+				ID:     dep.ID,
+				Weight: models.PlanWeight(plan),
+				User:   user,
+			})
+		}
+	}
+	err := deployment.NewInstances(d, deploy).StartScheduled(ctx, time.Now(), start)
+
+	if err != nil {
+		log.WithError(err).Error("Errored while starting scheduled deployments")
+	}
+}
+
+func stopExpiredDeployments() {
+	log.Printf("stopping expired deployments")
+	d := models.DeploymentDataSource(db)
+	ctx := context.Background()
+
+	err := deployment.NewInstances(d, deploy).StopExpired(ctx, time.Now())
+
+	if err != nil {
+		log.WithError(err).Error("Errored while stopping expired deployments")
+	}
+}
+
+func collectGarbage() {
+	log.Printf("removing expired build objects")
+	collector := retention.Collector{
+		Storage: storageService,
+		Repo:    models.RetentionDataSource(db),
+		Config:  retentionConfig,
+	}
+
+	report, err := collector.Collect(time.Now())
+	fields := log.Fields{
+		"dry_run": report.DryRun,
+		"objects": len(report.Keys),
+		"bytes":   report.Bytes,
+	}
+	if err != nil {
+		log.WithError(err).WithFields(fields).Error("Errored while removing expired build objects")
+		return
+	}
+	log.WithFields(fields).Info("removed expired build objects")
+}
+
+func deliverWebhooks() {
+	log.Printf("delivering webhooks")
+	deliverer := webhooks.Deliverer{
+		Repo:   models.WebhookDataSource(db),
+		Client: webhooks.NewClient(10 * time.Second),
+	}
+
+	err := deliverer.DeliverDue(context.Background(), 100)
+	if err != nil {
+		log.WithError(err).Error("Errored while delivering webhooks")
+	}
+}
+
 func generatedAFIs() {
 	log.Printf("checking afis")
 	watcher := afiwatcher.AFIWatcher{
@@ -167,6 +321,31 @@ func getBatchJobLogNames() {
 	}
 }
 
+func retryFailedBatchJobs() {
+	log.Printf("retrying failed batch jobs")
+	retrier := &batchretry.Retrier{
+		Backend:   recoBatch.Backend(),
+		BatchRepo: models.BatchDataSource(db),
+		Resubmit: func(kind string, id string) (string, error) {
+			runner := queue.BatchRunner{
+				Type:       kind,
+				APIBaseURL: apiBaseURL,
+				Batch:      recoBatch,
+				DB:         db,
+			}
+			return runner.Resubmit(id)
+		},
+		MaxAttempts: retryConfig.MaxAttempts,
+	}
+
+	// AWS Batch forgets jobs a day after they finish
+	sinceTime := time.Now().Add(-24 * time.Hour)
+	err := retrier.RetryFailed(context.Background(), sinceTime)
+	if err != nil {
+		log.WithError(err).Error("Errored while retrying failed batch jobs")
+	}
+}
+
 func checkHours() {
 	log.Printf("checking for users exceeding their subscription hours")
 	err := billing_hours.CheckUserHours(models.SubscriptionDataSource(db), models.DeploymentDataSource(db), deploy)
@@ -106,7 +106,7 @@ func (dh dockerHelper) Wait() {
 // hang around since it is the only place the logs exist.
 func (dh dockerHelper) ArchiveLogAndRemoveContainer(extra io.Reader) {
 	// Grab log, put in S3.
-	rc, err := dh.Logs(context.Background())
+	rc, err := dh.Logs(context.Background(), false)
 	if err != nil {
 		log.Printf("dockerHelper.Wait: dh.Logs: %v", err)
 		return
@@ -168,12 +168,14 @@ func (dh dockerHelper) Run() {
 	dh.Wait()
 }
 
-func (dh dockerHelper) Logs(ctx context.Context) (io.ReadCloser, error) {
+// Logs returns the container's logs, and what's added to them until it
+// stops if follow is true.
+func (dh dockerHelper) Logs(ctx context.Context, follow bool) (io.ReadCloser, error) {
 	rawLogs, err := dh.client.ContainerLogs(
 		ctx,
 		dh.id,
 		types.ContainerLogsOptions{
-			Follow:     true,
+			Follow:     follow,
 			ShowStderr: true,
 			ShowStdout: true,
 		},
@@ -377,10 +377,13 @@ func (h *handler) Logs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ?follow=false returns the logs so far, rather than waiting for the
+	// job to finish
+	follow := r.URL.Query().Get("follow") != "false"
 	rc, err := dockerHelper{
 		client: h.dockerClient,
 		id:     jobID,
-	}.Logs(r.Context())
+	}.Logs(r.Context(), follow)
 	if err != nil {
 		log.Printf("Logs: ContainerLogs: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
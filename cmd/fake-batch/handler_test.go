@@ -25,7 +25,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
 
-	"github.com/ReconfigureIO/platform/service/fakebatchlogs"
+	"github.com/ReconfigureIO/platform/service/logs"
 	"github.com/ReconfigureIO/platform/service/storage/localfile"
 )
 
@@ -194,7 +194,7 @@ func TestHandler(t *testing.T) {
 			"sim":   2,
 		}),
 
-		storage: localfile.Service("./logs/"),
+		storage: localfile.Service{Dir: "./logs/"},
 	}
 
 	s := httptest.NewServer(handler)
@@ -230,18 +230,22 @@ func TestHandler(t *testing.T) {
 
 			jobID := *respSubmitJob.JobId
 
-			logService := fakebatchlogs.Service{
+			logService := logs.Archive{
 				Endpoint: s.URL,
 			}
 			for j := 0; j < numFollowers; j++ {
 				wg.Add(1)
 				go func() {
 					defer wg.Done()
-					logReader := logService.Stream(context.Background(), jobID)
+					logReader, err := logService.Follow(context.Background(), jobID, nil)
+					if err != nil {
+						t.Errorf("logService.Follow: %v", err)
+						return
+					}
 					defer logReader.Close()
 
 					logCheckHasher := md5.New()
-					_, err := io.Copy(logCheckHasher, logReader)
+					_, err = io.Copy(logCheckHasher, logReader)
 					if err != nil {
 						log.Println("Error")
 						t.Fatalf("io.Copy: %v", err)
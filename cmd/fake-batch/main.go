@@ -3,8 +3,10 @@ package main
 import (
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 
+	"github.com/dchest/uniuri"
 	"github.com/docker/docker/client"
 
 	"github.com/ReconfigureIO/platform/service/storage/localfile"
@@ -31,6 +33,13 @@ func main() {
 		},
 	}
 
+	// Pre-signed URLs for the logs are served by fake-batch itself.
+	storage := localfile.Service{
+		Dir:     "./logs/",
+		BaseURL: url.URL{Scheme: "http", Host: "localhost:9090", Path: "/storage"},
+		Secret:  []byte(uniuri.NewLen(32)),
+	}
+
 	handler := &handler{
 		dockerClient:   dockerClient,
 		dockerState:    NewDockerState(),
@@ -42,7 +51,7 @@ func main() {
 			"sim":   2,
 		}),
 
-		storage: localfile.Service("./logs/"),
+		storage: storage,
 	}
 
 	// We just started, but we should deal with the case that docker was running
@@ -50,5 +59,9 @@ func main() {
 	// Fill queue slots with whatever is currently running in docker.
 	handler.enqueuePreexistingContainers()
 
-	log.Fatal(http.ListenAndServe(":9090", handler))
+	mux := http.NewServeMux()
+	mux.Handle("/storage/", http.StripPrefix("/storage", storage.Handler()))
+	mux.Handle("/", handler)
+
+	log.Fatal(http.ListenAndServe(":9090", mux))
 }
@@ -4,8 +4,12 @@ import (
 	"github.com/caarlos0/env"
 
 	"github.com/ReconfigureIO/platform/service/aws"
+	"github.com/ReconfigureIO/platform/service/batch"
+	"github.com/ReconfigureIO/platform/service/batchretry"
 	"github.com/ReconfigureIO/platform/service/deployment"
 	"github.com/ReconfigureIO/platform/service/events"
+	"github.com/ReconfigureIO/platform/service/queue"
+	"github.com/ReconfigureIO/platform/service/retention"
 	stripe "github.com/stripe/stripe-go"
 )
 
@@ -26,11 +30,16 @@ type RecoConfig struct {
 	LogzioToken             string `env:"LOGZIO_TOKEN"`
 	FeatureIntercom         bool   `env:"RECO_FEATURE_INTERCOM"`
 	FeatureDepQueue         bool   `env:"RECO_FEATURE_DEP_QUEUE"`
+	FeatureBatchQueue       bool   `env:"RECO_FEATURE_BATCH_QUEUE"`
 	FeatureUseSpotInstances bool   `env:"RECO_FEATURE_USE_SPOT_INSTANCES"`
 	StorageBucket           string `env:"RECO_AWS_BUCKET" envDefault:"reconfigureio-builds"`
 	AWS                     aws.ServiceConfig
+	Batch                   batch.Config
 	Deploy                  deployment.ServiceConfig
 	Intercom                events.IntercomConfig
+	Queue                   queue.Config
+	Retention               retention.Config
+	Retry                   batchretry.Config
 }
 
 func ParseEnvConfig() (*Config, error) {
@@ -51,6 +60,11 @@ func ParseEnvConfig() (*Config, error) {
 		return nil, err
 	}
 
+	err = env.Parse(&conf.Reco.Batch)
+	if err != nil {
+		return nil, err
+	}
+
 	err = env.Parse(&conf.Reco.Deploy)
 	if err != nil {
 		return nil, err
@@ -61,6 +75,21 @@ func ParseEnvConfig() (*Config, error) {
 		return nil, err
 	}
 
+	err = env.Parse(&conf.Reco.Queue)
+	if err != nil {
+		return nil, err
+	}
+
+	err = env.Parse(&conf.Reco.Retention)
+	if err != nil {
+		return nil, err
+	}
+
+	err = env.Parse(&conf.Reco.Retry)
+	if err != nil {
+		return nil, err
+	}
+
 	stripe.Key = conf.StripeKey
 
 	return &conf, nil
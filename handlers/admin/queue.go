@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/queue"
+	"github.com/ReconfigureIO/platform/sugar"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// QueueAdmin lets admins see and reprioritise queued jobs.
+type QueueAdmin struct {
+	DB *gorm.DB
+}
+
+// List lists the jobs of a type, deployment by default, with a status,
+// QUEUED by default, highest priority first.
+func (q QueueAdmin) List(c *gin.Context) {
+	jobType := c.DefaultQuery("type", "deployment")
+	status := c.DefaultQuery("status", models.StatusQueued)
+
+	qs := queue.NewQueueService(q.DB)
+	entries, err := qs.List(jobType, status)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+	sugar.SuccessResponse(c, 200, entries)
+}
+
+// Update reprioritises a job which is yet to be dispatched.
+func (q QueueAdmin) Update(c *gin.Context) {
+	post := models.PutQueueEntry{}
+	c.BindJSON(&post)
+	if !sugar.ValidateRequest(c, post) {
+		return
+	}
+	if *post.Weight < 0 {
+		sugar.ErrResponse(c, http.StatusBadRequest, "Weight must not be negative")
+		return
+	}
+
+	qs := queue.NewQueueService(q.DB)
+	entry, err := qs.SetWeight(c.Param("id"), *post.Weight)
+	if err != nil {
+		sugar.NotFoundOrError(c, err)
+		return
+	}
+	sugar.SuccessResponse(c, 200, entry)
+}
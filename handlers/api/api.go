@@ -2,7 +2,9 @@ package api
 
 import (
 	"errors"
+	"net/http"
 
+	"github.com/ReconfigureIO/platform/models"
 	"github.com/ReconfigureIO/platform/service/queue"
 	"github.com/ReconfigureIO/platform/sugar"
 	"github.com/gin-gonic/gin"
@@ -19,6 +21,10 @@ var (
 	db *gorm.DB
 
 	deploymentQueue queue.Queue
+
+	buildQueue      queue.Queue
+	simulationQueue queue.Queue
+	graphQueue      queue.Queue
 )
 
 // DB sets the database to use for the API.
@@ -31,6 +37,14 @@ func DepQueue(q queue.Queue) {
 	deploymentQueue = q
 }
 
+// BatchQueues sets the queues of builds, simulations and graphs. Without
+// them, their batch jobs are submitted as soon as their input is uploaded.
+func BatchQueues(build, simulation, graph queue.Queue) {
+	buildQueue = build
+	simulationQueue = simulation
+	graphQueue = graph
+}
+
 // Transaction runs a transaction, rolling back if error != nil.
 func Transaction(c *gin.Context, ops func(db *gorm.DB) error) error {
 	tx := db.Begin()
@@ -44,6 +58,26 @@ func Transaction(c *gin.Context, ops func(db *gorm.DB) error) error {
 	return err
 }
 
+// isReadOnly returns if the request can't modify anything.
+func isReadOnly(c *gin.Context) bool {
+	if c.Request == nil {
+		return true
+	}
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// accessRoles returns the organisation roles which may make the request.
+func accessRoles(c *gin.Context) []string {
+	if isReadOnly(c) {
+		return models.RolesRead
+	}
+	return models.RolesWrite
+}
+
 func bindID(c *gin.Context, id *string) bool {
 	paramID := c.Param("id")
 	if paramID != "" {
@@ -1,10 +1,13 @@
 package api
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/ReconfigureIO/platform/models"
 	"github.com/ReconfigureIO/platform/service/batch"
+	"github.com/ReconfigureIO/platform/sugar"
+	"github.com/gin-gonic/gin"
 )
 
 // BatchService is aws batch job service.
@@ -34,10 +37,22 @@ func (b BatchService) AddEvent(batchJob *models.BatchJob, event models.PostBatch
 	}
 
 	if newEvent.Status == models.StatusTerminating {
-		err = b.AWS.HaltJob(batchJob.BatchID)
+		// the job may have been submitted from its queue since it was
+		// loaded, in which case it's halted here, as its runner may have
+		// checked it was still running before this event was added
+		var current models.BatchJob
+		err = db.Select("batch_id").First(&current, batchJob.ID).Error
 		if err != nil {
 			return models.BatchJobEvent{}, err
 		}
+		batchJob.BatchID = current.BatchID
+		// jobs still waiting in a queue have nothing to halt
+		if batchJob.BatchID != "" {
+			err = b.AWS.HaltJob(batchJob.BatchID)
+			if err != nil {
+				return models.BatchJobEvent{}, err
+			}
+		}
 
 		terminated := models.BatchJobEvent{
 			Timestamp: time.Now(),
@@ -49,3 +64,41 @@ func (b BatchService) AddEvent(batchJob *models.BatchJob, event models.PostBatch
 	}
 	return newEvent, nil
 }
+
+// Cancel halts a user's batch job, recording TERMINATING and TERMINATED
+// events. Cancelling a job which has already finished succeeds without
+// halting anything, and cancelled is false. Errors are written to the
+// response, with ok false.
+func (b BatchService) Cancel(c *gin.Context, batchJob *models.BatchJob, kind string) (cancelled bool, ok bool) {
+	if batchJob.HasFinished() {
+		return false, true
+	}
+
+	currentStatus := batchJob.Status()
+	if batchJob.ID == 0 {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("%s is '%s', it has no job to cancel", kind, currentStatus))
+		return false, false
+	}
+
+	if !models.CanTransition(currentStatus, models.StatusTerminating) {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("%s not valid when current status is %s", models.StatusTerminating, currentStatus))
+		return false, false
+	}
+
+	event := models.PostBatchEvent{
+		Status:  models.StatusTerminating,
+		Message: kind + " cancelled by user",
+	}
+	_, err := b.AddEvent(batchJob, event)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return false, false
+	}
+
+	err = refreshBatchJobEvents(batchJob, db)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return false, false
+	}
+	return true, true
+}
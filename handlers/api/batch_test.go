@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/batch"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+)
+
+func TestCancelFinishedBatchJob(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// No calls to HaltJob are expected.
+	batchService := batch.NewMockService(mockCtrl)
+
+	batchJob := models.BatchJob{
+		ID:      1,
+		BatchID: "foobar",
+		Events: []models.BatchJobEvent{
+			{Status: models.StatusQueued},
+			{Status: models.StatusCompleted},
+		},
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	cancelled, ok := (BatchService{AWS: batchService}).Cancel(c, &batchJob, "Build")
+	if !ok {
+		t.Error("Expected cancelling a finished job to succeed, got: ", c.Writer.Status())
+	}
+	if cancelled {
+		t.Error("Expected a finished job not to be reported as cancelled")
+	}
+}
+
+func TestCancelWithoutBatchJob(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	batchService := batch.NewMockService(mockCtrl)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if _, ok := (BatchService{AWS: batchService}).Cancel(c, &models.BatchJob{}, "Build"); ok {
+		t.Error("Expected cancelling a job which was never submitted to fail")
+	}
+	if c.Writer.Status() != 400 {
+		t.Error("Expected 400 status, got: ", c.Writer.Status())
+	}
+}
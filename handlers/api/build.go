@@ -1,10 +1,13 @@
 package api
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/ReconfigureIO/platform/service/batch"
 	"github.com/ReconfigureIO/platform/service/storage"
@@ -19,6 +22,66 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+const (
+	// artifactURLExpiry is how long pre-signed artifact URLs are valid for.
+	artifactURLExpiry = 5 * time.Minute
+	// inputURLExpiry is how long pre-signed input URLs are valid for.
+	inputURLExpiry = time.Hour
+)
+
+// UploadURL is a short-lived URL to upload to with Method.
+type UploadURL struct {
+	URL       string    `json:"url"`
+	Method    string    `json:"method"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// presignInput responds with a URL to upload the input at key to directly.
+func presignInput(c *gin.Context, store storage.Service, key string) {
+	signed, err := store.PresignPut(key, inputURLExpiry)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, UploadURL{
+		URL:       signed,
+		Method:    "PUT",
+		ExpiresAt: time.Now().Add(inputURLExpiry),
+	})
+}
+
+// inputExists returns if there's an object at key in store. It's listed
+// rather than downloaded, so a missing object isn't confused with a
+// storage error.
+func inputExists(store storage.Service, key string) (bool, error) {
+	objects, err := store.List(key)
+	if err != nil {
+		return false, err
+	}
+	for _, object := range objects {
+		if object.Key == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// inputUploaded returns if the input at key has been uploaded, responding
+// with 400 if it hasn't.
+func inputUploaded(c *gin.Context, store storage.Service, key string, kind string) bool {
+	exists, err := inputExists(store, key)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return false
+	}
+	if !exists {
+		sugar.ErrResponse(c, 400, kind+" input has not been uploaded")
+		return false
+	}
+	return true
+}
+
 // Build handles requests for builds.
 type Build struct {
 	APIBaseURL      url.URL
@@ -36,14 +99,17 @@ func (b Build) Preload(db *gorm.DB) *gorm.DB {
 		Preload("BatchJob").
 		Preload("BatchJob.Events", func(db *gorm.DB) *gorm.DB {
 			return db.Order("timestamp ASC")
+		}).
+		Preload("BatchJob.Attempts", func(db *gorm.DB) *gorm.DB {
+			return db.Order("attempt ASC")
 		})
 }
 
 // Query fetches builds for user and project.
 func (b Build) Query(c *gin.Context) *gorm.DB {
 	user := middleware.GetUser(c)
-	joined := db.Joins("join projects on projects.id = builds.project_id").
-		Where("projects.user_id=?", user.ID)
+	joined := models.AccessibleBy(db.Joins("join projects on projects.id = builds.project_id"),
+		"projects.user_id", user.ID, accessRoles(c))
 	return b.Preload(joined)
 }
 
@@ -58,11 +124,16 @@ func (b Build) QueryWhere(where ...interface{}) *gorm.DB {
 
 // ByID gets the first build by ID, 404 if it doesn't exist.
 func (b Build) ByID(c *gin.Context) (models.Build, error) {
-	build := models.Build{}
 	var id string
 	if !bindID(c, &id) {
-		return build, errNotFound
+		return models.Build{}, errNotFound
 	}
+	return b.byID(c, id)
+}
+
+// byID is ByID for an ID which isn't the request's.
+func (b Build) byID(c *gin.Context, id string) (models.Build, error) {
+	build := models.Build{}
 	err := b.Query(c).First(&build, "builds.id = ?", id).Error
 	// Not found? Might be a public build ID
 	if err == gorm.ErrRecordNotFound {
@@ -149,6 +220,42 @@ func (b Build) Report(c *gin.Context) {
 	sugar.SuccessResponse(c, 200, report)
 }
 
+// ReportDiff compares the utilisation of a build with the build given by
+// the against query parameter.
+func (b Build) ReportDiff(c *gin.Context) {
+	againstID := c.Query("against")
+	if againstID == "" {
+		sugar.ErrResponse(c, 400, "Missing against parameter")
+		return
+	}
+
+	build, err := b.ByID(c)
+	if err != nil {
+		return
+	}
+	against, err := b.byID(c, againstID)
+	if err != nil {
+		return
+	}
+
+	buildRepo := models.BuildDataSource(db)
+	reports := make([]models.Report, 2)
+	for i, bld := range []models.Build{against, build} {
+		stored, err := buildRepo.GetBuildReport(bld)
+		if err != nil {
+			sugar.NotFoundOrError(c, err)
+			return
+		}
+		reports[i], err = stored.Parse()
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+	}
+
+	sugar.SuccessResponse(c, 200, models.DiffReports(against.ID, reports[0], build.ID, reports[1]))
+}
+
 // Get fetches a build.
 func (b Build) Get(c *gin.Context) {
 	build, err := b.ByID(c)
@@ -175,7 +282,29 @@ func (b Build) Create(c *gin.Context) {
 		return
 	}
 
-	newBuild := models.Build{Project: project, Message: post.Message, Token: uniuri.NewLen(64)}
+	if post.Target == "" {
+		post.Target = models.DefaultTargetID
+	}
+	if _, ok := models.LookupTarget(post.Target); !ok {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Unsupported target '%s', see /targets for those supported", post.Target))
+		return
+	}
+
+	if post.Tier == "" {
+		post.Tier = models.DefaultBuildTierID
+	}
+	if !checkBuildTier(c, post.Tier) {
+		return
+	}
+
+	newBuild := models.Build{
+		Project: project,
+		Message: post.Message,
+		Target:  post.Target,
+		Tier:    post.Tier,
+		SkipAFI: post.SkipAFI,
+		Token:   uniuri.NewLen(64),
+	}
 	if err := db.Create(&newBuild).Error; err != nil {
 		sugar.InternalError(c, err)
 		return
@@ -184,6 +313,24 @@ func (b Build) Create(c *gin.Context) {
 	sugar.SuccessResponse(c, 201, newBuild)
 }
 
+// checkBuildTier checks the user may build with tier on their plan. Errors
+// are written to the response, with false returned.
+func checkBuildTier(c *gin.Context, tier string) bool {
+	if _, ok := models.LookupBuildTier(tier); !ok {
+		ids := []string{}
+		for _, t := range models.BuildTiers {
+			ids = append(ids, t.ID)
+		}
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Unsupported tier '%s', expected one of %s", tier, strings.Join(ids, ", ")))
+		return false
+	}
+	if !models.PlanAllowsBuildTier(userPlan(middleware.GetUser(c)), tier) {
+		sugar.ErrResponse(c, http.StatusPaymentRequired, fmt.Sprintf("The '%s' tier isn't available on your plan", tier))
+		return false
+	}
+	return true
+}
+
 // Input handles build inputs.
 func (b Build) Input(c *gin.Context) {
 	var id string
@@ -213,6 +360,131 @@ func (b Build) Input(c *gin.Context) {
 		return
 	}
 
+	b.start(c, build)
+}
+
+// InputURL hands out a URL to upload a build's input to directly, instead
+// of through Input. Once uploaded, InputComplete starts the build.
+func (b Build) InputURL(c *gin.Context) {
+	build, err := b.ByID(c)
+	if err != nil {
+		return
+	}
+
+	if build.Status() != models.StatusSubmitted {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Build is '%s', not SUBMITTED", build.Status()))
+		return
+	}
+
+	presignInput(c, b.Storage, build.InputUrl())
+}
+
+// InputComplete starts a build whose input was uploaded to a URL from
+// InputURL.
+func (b Build) InputComplete(c *gin.Context) {
+	build, err := b.ByID(c)
+	if err != nil {
+		return
+	}
+
+	if build.Status() != models.StatusSubmitted {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Build is '%s', not SUBMITTED", build.Status()))
+		return
+	}
+
+	if !inputUploaded(c, b.Storage, build.InputUrl(), "Build") {
+		return
+	}
+
+	b.start(c, build)
+}
+
+// Rebuild starts a new build of an existing build's input, which is
+// copied in storage rather than uploaded again.
+func (b Build) Rebuild(c *gin.Context) {
+	var id string
+	if !bindID(c, &id) {
+		return
+	}
+	// the body is optional
+	post := models.PostRebuild{}
+	if c.Request.ContentLength != 0 {
+		c.BindJSON(&post)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	// Only the owner may rebuild, public builds are excluded.
+	original := models.Build{}
+	err := b.Query(c).First(&original, "builds.id = ?", id).Error
+	if err != nil {
+		sugar.NotFoundOrError(c, err)
+		return
+	}
+	if original.Status() == models.StatusSubmitted {
+		sugar.ErrResponse(c, 400, "Build has no input to rebuild")
+		return
+	}
+	exists, err := inputExists(b.Storage, original.InputUrl())
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+	if !exists {
+		sugar.ErrResponse(c, 400, "Build input is no longer available")
+		return
+	}
+
+	message := original.Message
+	if post.Message != nil {
+		message = *post.Message
+	}
+	// the user's plan may have changed since
+	tier := original.BuildTier().ID
+	if !checkBuildTier(c, tier) {
+		return
+	}
+
+	newBuild := models.Build{
+		Project: original.Project,
+		Message: message,
+		Target:  original.Target,
+		Tier:    tier,
+		SkipAFI: original.SkipAFI,
+		Token:   uniuri.NewLen(64),
+	}
+	if err := db.Create(&newBuild).Error; err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+	// the build's input is keyed by its ID, so it's copied once created,
+	// and the build removed if it can't be
+	err = b.Storage.Copy(original.InputUrl(), newBuild.InputUrl())
+	if err != nil {
+		db.Delete(&newBuild)
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.EnqueueEvent(b.Events, c, "Posted Build", newBuild.Project.UserID, map[string]interface{}{"build_id": newBuild.ID, "project_name": newBuild.Project.Name, "rebuild_of": original.ID})
+	b.start(c, newBuild)
+}
+
+// start runs the batch job of a build whose input has been uploaded, or
+// queues it to be run.
+func (b Build) start(c *gin.Context, build models.Build) {
+	if buildQueue != nil {
+		user := middleware.GetUser(c)
+		queued := pushJob(c, buildQueue, "build", build.ID, user, func() error {
+			return b.Repo.AddBatchJobToBuild(&build, b.BatchRepo.New(""))
+		})
+		if queued {
+			sugar.SuccessResponse(c, 200, build)
+		}
+		return
+	}
+
 	urlEvents, urlReports := b.APIBaseURL, b.APIBaseURL
 	urlEvents.RawQuery = fmt.Sprintf("token=%s", build.Token)
 	urlReports.RawQuery = fmt.Sprintf("token=%s", build.Token)
@@ -234,6 +506,38 @@ func (b Build) Input(c *gin.Context) {
 	sugar.SuccessResponse(c, 200, build)
 }
 
+// Delete cancels a build, halting its batch job.
+func (b Build) Delete(c *gin.Context) {
+	build := models.Build{}
+	var id string
+	if !bindID(c, &id) {
+		return
+	}
+	// Only the owner may cancel, public builds are excluded.
+	err := b.Query(c).First(&build, "builds.id = ?", id).Error
+	if err != nil {
+		sugar.NotFoundOrError(c, err)
+		return
+	}
+
+	cancelled, ok := (BatchService{AWS: b.AWS}).Cancel(c, &build.BatchJob, "Build")
+	if !ok {
+		return
+	}
+	if buildQueue != nil {
+		err = buildQueue.Remove(build.ID)
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+	}
+
+	if cancelled {
+		sugar.EnqueueEvent(b.Events, c, "Cancelled Build", build.Project.UserID, map[string]interface{}{"build_id": build.ID, "project_name": build.Project.Name})
+	}
+	sugar.SuccessResponse(c, 200, build)
+}
+
 // Logs stream logs for builds.
 func (b Build) Logs(c *gin.Context) {
 	build, err := b.ByID(c)
@@ -329,54 +633,103 @@ func (b Build) CreateReport(c *gin.Context) {
 }
 
 func (b Build) canDownloadArtifact(c *gin.Context, build models.Build) bool {
-	user, loggedIn := middleware.CheckUser(c)
-	if loggedIn && build.Project.UserID == user.ID {
-		return true
-	}
 	token, exists := c.GetQuery("token")
 	if exists && build.Token == token {
 		return true
 	}
-	return false
+	if build.Project.ID == b.PublicProjectID {
+		return true
+	}
+	if _, loggedIn := middleware.CheckUser(c); !loggedIn {
+		return false
+	}
+	// the same builds a user can fetch with ByID
+	var count int
+	err := b.Query(c).Model(&models.Build{}).Where("builds.id = ?", build.ID).Count(&count).Error
+	return err == nil && count > 0
 }
 
+// DownloadArtifact downloads the artifacts of a completed build.
 func (b Build) DownloadArtifact(c *gin.Context) {
-	build, err := b.unauthOne(c)
-	if err != nil {
-		c.AbortWithError(404, err)
+	build, ok := b.downloadable(c)
+	if !ok {
 		return
 	}
 
-	if !b.canDownloadArtifact(c, build) {
-		c.AbortWithStatus(403)
+	if build.Status() != models.StatusCompleted {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Build is '%s', not COMPLETED", build.Status()))
 		return
 	}
 
-	if build.Status() != "COMPLETED" {
-		sugar.ErrResponse(c, 400, fmt.Sprintf("Build is '%s', not COMPLETED", build.Status()))
+	b.download(c, build.ArtifactUrl(), build.ID+"-artifacts.zip")
+}
+
+// DownloadDebug downloads the debug artifacts, such as logs and reports, of
+// a finished build.
+func (b Build) DownloadDebug(c *gin.Context) {
+	build, ok := b.downloadable(c)
+	if !ok {
 		return
 	}
 
-	object, err := b.Storage.Download(build.ArtifactUrl())
-	if object != nil {
-		defer func() {
-			err := object.Close()
-			if err != nil {
-				log.WithError(err).Error("Failed to close b.Storage.Download")
-			}
-		}()
+	if !build.HasFinished() {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Build is '%s', it has not finished", build.Status()))
+		return
 	}
+
+	b.download(c, build.DebugUrl(), build.ID+"-debug.zip")
+}
+
+func (b Build) downloadable(c *gin.Context) (models.Build, bool) {
+	build, err := b.unauthOne(c)
 	if err != nil {
-		sugar.InternalError(c, err)
+		sugar.NotFoundOrError(c, err)
+		return build, false
+	}
+
+	if !b.canDownloadArtifact(c, build) {
+		c.AbortWithStatus(403)
+		return build, false
+	}
+	return build, true
+}
+
+// ArtifactURL is a short-lived URL to download a build's artifacts from.
+type ArtifactURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// download streams key to the client, or with ?url=true, responds with a
+// pre-signed URL to download it directly from storage.
+func (b Build) download(c *gin.Context, key string, filename string) {
+	if c.Query("url") == "true" {
+		signed, err := b.Storage.PresignGet(key, artifactURLExpiry)
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+		sugar.SuccessResponse(c, 200, ArtifactURL{URL: signed, ExpiresAt: time.Now().Add(artifactURLExpiry)})
 		return
 	}
 
-	buf := new(bytes.Buffer)
-	_, err = buf.ReadFrom(object)
+	object, err := b.Storage.Download(key)
 	if err != nil {
-		sugar.InternalError(c, err)
+		sugar.NotFoundOrError(c, err)
 		return
 	}
-
-	c.Data(200, "application/zip", buf.Bytes())
+	defer func() {
+		err := object.Close()
+		if err != nil {
+			log.WithError(err).Error("Failed to close b.Storage.Download")
+		}
+	}()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(200)
+	_, err = io.Copy(c.Writer, object)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{"key": key}).Error("Failed to stream artifact")
+	}
 }
@@ -4,6 +4,7 @@ package api
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/ReconfigureIO/platform/models"
 	"github.com/ReconfigureIO/platform/service/batch"
+	"github.com/ReconfigureIO/platform/service/events"
 	"github.com/ReconfigureIO/platform/service/storage"
 )
 
@@ -149,6 +151,51 @@ func TestDownloadArtifact(t *testing.T) {
 	})
 }
 
+func TestDownloadArtifactURL(t *testing.T) {
+	models.RunTransaction(func(db *gorm.DB) {
+		DB(db)
+
+		build := models.Build{
+			Token:   "foobar",
+			Project: models.Project{Name: "reco-examples"},
+			BatchJob: models.BatchJob{
+				ID: 2,
+				Events: []models.BatchJobEvent{
+					{ID: "4", BatchJobID: 2, Timestamp: time.Now(), Status: models.StatusCompleted},
+				},
+			},
+		}
+		if err := db.Create(&build).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		storageService := storage.NewMockService(mockCtrl)
+		storageService.EXPECT().PresignGet("builds/"+build.ID+"/debug.zip", artifactURLExpiry).Return("https://example.com/builds/"+build.ID+"/debug.zip", nil)
+
+		b := Build{
+			Storage:         storageService,
+			PublicProjectID: build.Project.ID,
+		}
+		r := gin.Default()
+		r.GET("builds/:id/debug", b.DownloadDebug)
+
+		// Anyone can download public builds
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/builds/"+build.ID+"/debug?url=true", nil)
+		r.ServeHTTP(w, req)
+
+		if w.Code != 200 {
+			t.Fatalf("Could not get debug artifact URL, response code: %v", w.Code)
+		}
+		expected := "https://example.com/builds/" + build.ID + "/debug.zip"
+		if !bytes.Contains(w.Body.Bytes(), []byte(expected)) {
+			t.Fatalf("Expected response to contain %s, got %s", expected, w.Body.String())
+		}
+	})
+}
+
 func TestBuildInput(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -200,3 +247,112 @@ func TestBuildInput(t *testing.T) {
 	req.SetBasicAuth(strconv.Itoa(build.Project.User.GithubID), build.Project.User.Token)
 	r.ServeHTTP(w, req)
 }
+
+func TestBuildRebuild(t *testing.T) {
+	models.RunTransaction(func(db *gorm.DB) {
+		DB(db)
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		storageService := storage.NewMockService(mockCtrl)
+		batchRepo := models.NewMockBatchRepo(mockCtrl)
+		buildRepo := models.NewMockBuildRepo(mockCtrl)
+		batchService := batch.NewMockService(mockCtrl)
+
+		user := models.User{ID: "rebuild-user"}
+		original := models.Build{
+			Project: models.Project{User: user},
+			Message: "original",
+			BatchJob: models.BatchJob{
+				Events: []models.BatchJobEvent{
+					{Timestamp: time.Now(), Status: models.StatusQueued},
+					{Timestamp: time.Now(), Status: models.StatusCompleted},
+				},
+			},
+		}
+		if err := db.Create(&original).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		apiBuild := Build{
+			APIBaseURL: url.URL{Host: "localhost", Scheme: "https"},
+			Storage:    storageService,
+			Repo:       buildRepo,
+			BatchRepo:  batchRepo,
+			AWS:        batchService,
+			Events:     events.NewIntercomEventService(events.IntercomConfig{}, 1),
+		}
+
+		storageService.EXPECT().List(original.InputUrl()).Return([]storage.Object{{Key: original.InputUrl()}}, nil)
+		storageService.EXPECT().Copy(original.InputUrl(), gomock.Any()).Return(nil)
+		batchService.EXPECT().RunBuild(gomock.Any(), gomock.Any(), gomock.Any()).Return("rebuildBatchJobID", nil)
+		batchRepo.EXPECT().New("rebuildBatchJobID").Return(models.BatchJob{})
+		buildRepo.EXPECT().AddBatchJobToBuild(gomock.Any(), models.BatchJob{}).Return(nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/builds/"+original.ID+"/rebuild", bytes.NewBufferString(`{"message": "again"}`))
+		c.Request.Header.Add("Content-Type", "application/json")
+		c.Set("reco_user", user)
+		c.Params = append(c.Params, gin.Param{Key: "id", Value: original.ID})
+		apiBuild.Rebuild(c)
+		if w.Code != 200 {
+			t.Fatalf("Expected 200 status, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var rebuilt models.Build
+		err := db.Where("project_id = ? AND id <> ?", original.ProjectID, original.ID).First(&rebuilt).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rebuilt.Message != "again" {
+			t.Errorf("Expected rebuild's message to be overridden, got %q", rebuilt.Message)
+		}
+	})
+}
+
+func TestBuildRebuildCopyFails(t *testing.T) {
+	models.RunTransaction(func(db *gorm.DB) {
+		DB(db)
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		storageService := storage.NewMockService(mockCtrl)
+
+		user := models.User{ID: "rebuild-user"}
+		original := models.Build{
+			Project: models.Project{User: user},
+			BatchJob: models.BatchJob{
+				Events: []models.BatchJobEvent{
+					{Timestamp: time.Now(), Status: models.StatusQueued},
+					{Timestamp: time.Now(), Status: models.StatusCompleted},
+				},
+			},
+		}
+		if err := db.Create(&original).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		apiBuild := Build{
+			Storage: storageService,
+			Events:  events.NewIntercomEventService(events.IntercomConfig{}, 1),
+		}
+
+		storageService.EXPECT().List(original.InputUrl()).Return([]storage.Object{{Key: original.InputUrl()}}, nil)
+		storageService.EXPECT().Copy(original.InputUrl(), gomock.Any()).Return(errors.New("copy failed"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/builds/"+original.ID+"/rebuild", nil)
+		c.Set("reco_user", user)
+		c.Params = append(c.Params, gin.Param{Key: "id", Value: original.ID})
+		apiBuild.Rebuild(c)
+		if w.Code != 500 {
+			t.Fatalf("Expected 500 status, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var count int
+		db.Model(&models.Build{}).Where("project_id = ?", original.ProjectID).Count(&count)
+		if count != 1 {
+			t.Errorf("Expected the rebuild to be removed, got %d builds", count)
+		}
+	})
+}
@@ -7,8 +7,8 @@ import (
 	"net/url"
 	"time"
 
-	"github.com/ReconfigureIO/platform/service/batch"
 	"github.com/ReconfigureIO/platform/service/deployment"
+	"github.com/ReconfigureIO/platform/service/logs"
 	"github.com/ReconfigureIO/platform/service/storage"
 
 	"github.com/ReconfigureIO/platform/middleware"
@@ -32,7 +32,7 @@ type Deployment struct {
 	UseSpotInstances bool
 	Storage          storage.Service
 	DeployService    deployment.Service
-	AWS              batch.Service
+	LogSource        logs.LogSource
 	PublicProjectID  string
 }
 
@@ -45,7 +45,12 @@ func (d Deployment) Preload() *gorm.DB {
 func (d Deployment) Query(c *gin.Context) *gorm.DB {
 	user := middleware.GetUser(c)
 	dds := models.DeploymentDataSource(db)
-	return dds.Query(user.ID)
+	q := dds.Query(user.ID)
+	if !isReadOnly(c) {
+		// viewers of an organisation can see, but not stop, its deployments
+		q = models.AccessibleBy(q, "deployments.user_id", user.ID, models.RolesWrite)
+	}
+	return q
 }
 
 // ByID gets the first deployment by ID, 404 if it doesn't exist.
@@ -76,13 +81,22 @@ func (d Deployment) Create(c *gin.Context) {
 	// Ensure that the project exists, and the user has permissions for it
 	build := models.Build{}
 	user := middleware.GetUser(c)
-	err := Build{}.QueryWhere("projects.id=? OR projects.user_id=?", d.PublicProjectID, user.ID).
+	err := models.AccessibleBy(Build{}.QueryWhere(), "projects.user_id", user.ID, models.RolesWrite).
 		First(&build, "builds.id = ?", post.BuildID).Error
+	if err == gorm.ErrRecordNotFound {
+		err = Build{}.QueryWhere("projects.id=?", d.PublicProjectID).
+			First(&build, "builds.id = ?", post.BuildID).Error
+	}
 	if err != nil {
 		sugar.NotFoundOrError(c, err)
 		return
 	}
 
+	if build.SkipAFI {
+		sugar.ErrResponse(c, 400, "Build skipped generating an image, so can't be deployed")
+		return
+	}
+
 	// Ensure there is enough instance hours
 	billingService := Billing{}
 	billingHours := billingService.FetchBillingHours(user.ID)
@@ -94,8 +108,38 @@ func (d Deployment) Create(c *gin.Context) {
 		return
 	}
 
+	// the deployment's own backend wins over its project's
+	backend, err := deployment.Resolve(d.DeployService, post.Backend, build.Project.DeploymentBackend)
+	if err != nil {
+		sugar.ErrResponse(c, 400, "Unknown deployment backend")
+		return
+	}
+
+	// only EC2 deployments run in a region, on an instance type
+	var region, instanceType string
+	if backend == deployment.BackendEC2 {
+		conf := d.DeployService.GetServiceConfig()
+		region, instanceType, err = conf.Placement(post.Region, post.InstanceType)
+		if err != nil {
+			sugar.ErrResponse(c, 400, fmt.Sprintf("Invalid deployment: %s", err))
+			return
+		}
+	} else if post.Region != "" || post.InstanceType != "" {
+		sugar.ErrResponse(c, 400, "Regions and instance types only apply to EC2 deployments")
+		return
+	}
+
+	if post.MaxDuration < 0 {
+		sugar.ErrResponse(c, 400, "max_duration can't be negative")
+		return
+	}
+	// deployments starting now aren't scheduled
+	if post.StartAt != nil && !post.StartAt.After(time.Now()) {
+		post.StartAt = nil
+	}
+
 	useSpotInstance := d.UseSpotInstances
-	if build.Project.ID == d.PublicProjectID {
+	if build.Project.ID == d.PublicProjectID || backend != deployment.BackendEC2 {
 		useSpotInstance = false
 	}
 
@@ -106,10 +150,27 @@ func (d Deployment) Create(c *gin.Context) {
 		Token:        uniuri.NewLen(64),
 		SpotInstance: useSpotInstance,
 		UserID:       user.ID,
+		Backend:      backend,
+		Region:       region,
+		InstanceType: instanceType,
+		StartAt:      post.StartAt,
+		MaxDuration:  post.MaxDuration,
 	}
 
-	// use deployment queue if enabled
-	if deploymentQueue != nil {
+	if newDep.StartAt != nil {
+		// the cron worker starts scheduled deployments when it's time
+		newDep.Events = []models.DeploymentEvent{{
+			Timestamp: time.Now(),
+			Status:    models.StatusSubmitted,
+			Message:   fmt.Sprintf("Scheduled to start at %s", newDep.StartAt.UTC().Format(time.RFC3339)),
+		}}
+		err = db.Create(&newDep).Error
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+	} else if deploymentQueue != nil {
+		// use deployment queue if enabled
 		// check number of queued deployments owned by user.
 		if ad, err := deploymentQueue.CountUserJobsInStatus(user, models.StatusQueued); err != nil {
 			sugar.ErrResponse(c, http.StatusInternalServerError, "Error retrieving deployment information")
@@ -127,7 +188,8 @@ func (d Deployment) Create(c *gin.Context) {
 
 		deploymentQueue.Push(queue.Job{
 			ID:     newDep.ID,
-			Weight: 2, // TODO prioritize paying customers
+			Weight: models.PlanWeight(userPlan(user)),
+			User:   user,
 		})
 	} else {
 		dds := models.DeploymentDataSource(db)
@@ -155,7 +217,7 @@ func (d Deployment) Create(c *gin.Context) {
 			return
 		}
 
-		err = db.Model(&newDep).Update("InstanceID", instanceID).Error
+		err = dds.SetInstance(newDep, instanceID)
 
 		if err != nil {
 			sugar.InternalError(c, err)
@@ -163,7 +225,7 @@ func (d Deployment) Create(c *gin.Context) {
 		}
 
 		newEvent := models.DeploymentEvent{Timestamp: time.Now(), Status: "QUEUED"}
-		err = db.Model(&newDep).Association("Events").Append(newEvent).Error
+		err = dds.AddEvent(newDep, newEvent)
 
 		if err != nil {
 			sugar.InternalError(c, err)
@@ -215,16 +277,91 @@ func (d Deployment) Get(c *gin.Context) {
 	if err != nil {
 		return
 	}
+
+	if deploymentQueue != nil && !outputDep.HasStarted() {
+		status, err := deploymentQueueStatus()
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+		if p, ok := status.Position(outputDep.ID); ok {
+			outputDep.Queue = &p
+		}
+	}
 	sugar.SuccessResponse(c, 200, outputDep)
 }
 
+// Delete stops a deployment, terminating its instance if it has one.
+func (d Deployment) Delete(c *gin.Context) {
+	dep, err := d.ByID(c)
+	if err != nil {
+		return
+	}
+
+	// Stopping a finished deployment, or one already being stopped, is a
+	// no-op
+	currentStatus := dep.Status()
+	if dep.HasFinished() || currentStatus == models.StatusTerminating {
+		sugar.SuccessResponse(c, 200, dep)
+		return
+	}
+
+	if !models.CanTransition(currentStatus, models.StatusTerminating) {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("%s not valid when current status is %s", models.StatusTerminating, currentStatus))
+		return
+	}
+
+	// A deployment still waiting in the queue never gets an instance
+	if deploymentQueue != nil {
+		err = deploymentQueue.Remove(dep.ID)
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+	}
+
+	dds := models.DeploymentDataSource(db)
+	message := "Deployment stopped by user"
+	err = dds.AddEvent(dep, models.DeploymentEvent{Timestamp: time.Now(), Status: models.StatusTerminating, Message: message})
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	// If the instance can't be stopped now, the deployment is left
+	// TERMINATING, and the instance is stopped when its status is next
+	// updated.
+	if dep.InstanceID != "" {
+		err = d.DeployService.StopDeployment(c, dep)
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+	}
+
+	err = dds.AddEvent(dep, models.DeploymentEvent{Timestamp: time.Now(), Status: models.StatusTerminated, Message: message})
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	err = refreshDeploymentEvents(&dep, db)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.EnqueueEvent(d.Events, c, "Stopped Deployment", dep.UserID, map[string]interface{}{"deployment_id": dep.ID, "build_id": dep.BuildID})
+	sugar.SuccessResponse(c, 200, dep)
+}
+
 // Logs stream logs for deployments.
 func (d Deployment) Logs(c *gin.Context) {
 	targetDep, err := d.ByID(c)
 	if err != nil {
 		return
 	}
-	streamDeploymentLogs(d.DeployService, d.AWS, c, &targetDep)
+	streamDeploymentLogs(d.DeployService, d.LogSource, c, &targetDep)
 }
 
 func (d Deployment) canPostEvent(c *gin.Context, dep models.Deployment) bool {
@@ -293,7 +430,7 @@ func (d Deployment) AddEvent(c *gin.Context, dep models.Deployment, event models
 		Code:         event.Code,
 	}
 
-	err := db.Create(&newEvent).Error
+	err := models.DeploymentDataSource(db).AddEvent(dep, newEvent)
 	if err != nil {
 		return models.DeploymentEvent{}, err
 	}
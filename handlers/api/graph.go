@@ -31,14 +31,17 @@ func (g Graph) Preload(db *gorm.DB) *gorm.DB {
 		Preload("BatchJob").
 		Preload("BatchJob.Events", func(db *gorm.DB) *gorm.DB {
 			return db.Order("timestamp ASC")
+		}).
+		Preload("BatchJob.Attempts", func(db *gorm.DB) *gorm.DB {
+			return db.Order("attempt ASC")
 		})
 }
 
 // Query fetches graphs for user and project.
 func (g Graph) Query(c *gin.Context) *gorm.DB {
 	user := middleware.GetUser(c)
-	joined := db.Joins("join projects on projects.id = graphs.project_id").
-		Where("projects.user_id=?", user.ID)
+	joined := models.AccessibleBy(db.Joins("join projects on projects.id = graphs.project_id"),
+		"projects.user_id", user.ID, accessRoles(c))
 	return g.Preload(joined)
 }
 
@@ -142,6 +145,60 @@ func (g Graph) Input(c *gin.Context) {
 		return
 	}
 
+	g.start(c, graph)
+}
+
+// InputURL hands out a URL to upload a graph's input to directly, instead
+// of through Input. Once uploaded, InputComplete starts the graph.
+func (g Graph) InputURL(c *gin.Context) {
+	graph, err := g.ByID(c)
+	if err != nil {
+		return
+	}
+
+	if graph.Status() != models.StatusSubmitted {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Graph is '%s', not SUBMITTED", graph.Status()))
+		return
+	}
+
+	presignInput(c, g.Storage, graph.InputUrl())
+}
+
+// InputComplete starts a graph whose input was uploaded to a URL from
+// InputURL.
+func (g Graph) InputComplete(c *gin.Context) {
+	graph, err := g.ByID(c)
+	if err != nil {
+		return
+	}
+
+	if graph.Status() != models.StatusSubmitted {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Graph is '%s', not SUBMITTED", graph.Status()))
+		return
+	}
+
+	if !inputUploaded(c, g.Storage, graph.InputUrl(), "Graph") {
+		return
+	}
+
+	g.start(c, graph)
+}
+
+// start runs the batch job of a graph whose input has been uploaded, or
+// queues it to be run.
+func (g Graph) start(c *gin.Context, graph models.Graph) {
+	if graphQueue != nil {
+		user := middleware.GetUser(c)
+		queued := pushJob(c, graphQueue, "graph", graph.ID, user, func() error {
+			batchJob := BatchService{AWS: g.AWS}.New("")
+			return db.Model(&graph).Association("BatchJob").Append(batchJob).Error
+		})
+		if queued {
+			sugar.SuccessResponse(c, 200, graph)
+		}
+		return
+	}
+
 	urlEvents := g.APIBaseURL
 	urlEvents.RawQuery = fmt.Sprintf("token=%s", graph.Token)
 	urlEvents.Path = "/graphs/" + graph.ID + "/events"
@@ -164,6 +221,41 @@ func (g Graph) Input(c *gin.Context) {
 	sugar.SuccessResponse(c, 200, graph)
 }
 
+// Delete cancels a graph, halting its batch job.
+func (g Graph) Delete(c *gin.Context) {
+	graph, err := g.ByID(c)
+	if err != nil {
+		return
+	}
+
+	cancelled, ok := (BatchService{AWS: g.AWS}).Cancel(c, &graph.BatchJob, "Graph")
+	if !ok {
+		return
+	}
+	if graphQueue != nil {
+		err = graphQueue.Remove(graph.ID)
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+	}
+
+	if cancelled {
+		sugar.EnqueueEvent(g.Events, c, "Cancelled Graph", graph.Project.UserID, map[string]interface{}{"graph_id": graph.ID, "project_name": graph.Project.Name})
+	}
+	sugar.SuccessResponse(c, 200, graph)
+}
+
+// Logs stream logs for graph.
+func (g Graph) Logs(c *gin.Context) {
+	graph, err := g.ByID(c)
+	if err != nil {
+		return
+	}
+
+	StreamBatchLogs(g.AWS, c, &graph.BatchJob)
+}
+
 // Download returns the graph file.
 func (g Graph) Download(c *gin.Context) {
 	graph, err := g.ByID(c)
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"strconv"
 	"time"
 
 	"github.com/ReconfigureIO/platform/models"
 	"github.com/ReconfigureIO/platform/service/aws"
 	"github.com/ReconfigureIO/platform/service/batch"
 	"github.com/ReconfigureIO/platform/service/deployment"
+	"github.com/ReconfigureIO/platform/service/logs"
 	"github.com/ReconfigureIO/platform/service/stream"
 	"github.com/ReconfigureIO/platform/sugar"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
@@ -18,8 +20,23 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// StreamBatchLogs streams batch logs from AWS.
-func StreamBatchLogs(awsSession batch.Service, c *gin.Context, b *models.BatchJob) {
+// StreamBatchLogs streams batch logs from the job's backend, or serves a
+// range of them when requested.
+func StreamBatchLogs(batchService batch.Service, c *gin.Context, b *models.BatchJob) {
+	from, to, isRange, ok := logRange(c)
+	if !ok {
+		return
+	}
+	if isRange {
+		name, err := batchLogName(batchService, b)
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+		serveLogRange(c, batchService.Logs(), name, from, to)
+		return
+	}
+
 	ctx, cancel := WithClose(c)
 	defer cancel()
 
@@ -48,54 +65,77 @@ func StreamBatchLogs(awsSession batch.Service, c *gin.Context, b *models.BatchJo
 		return true
 	})
 
-	var logStream *cloudwatchlogs.LogStream
-	var err error
+	name, err := batchLogName(batchService, b)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	log.Printf("opening log stream: %s", name)
+
+	r, err := batchService.Logs().Follow(ctx, name, func() bool {
+		err := refreshBatchJobEvents(b, db)
+		return err != nil || b.HasFinished()
+	})
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+	defer r.Close()
+	streamReader(ctx, c, r)
+}
+
+// batchLogName returns the name of a batch job's logs, which is only
+// recorded on some backends' jobs.
+func batchLogName(batchService batch.Service, b *models.BatchJob) (string, error) {
 	if b.LogName != "" {
-		logStream, err = awsSession.GetJobStream(b.LogName)
-		if err != nil {
-			sugar.InternalError(c, err)
+		return b.LogName, nil
+	}
+	detail, err := batchService.GetJobDetail(b.BatchID)
+	if err != nil {
+		return "", err
+	}
+	return detail.LogName, nil
+}
+
+func streamDeploymentLogs(service deployment.Service, deploymentLogs logs.LogSource, c *gin.Context, deployment *models.Deployment) {
+	from, to, isRange, ok := logRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := WithClose(c)
+	defer cancel()
+
+	if isRange {
+		r, ok, err := deploymentLogReader(ctx, service, *deployment)
+		if ok {
+			if err != nil {
+				sugar.InternalError(c, err)
+				return
+			}
+			defer r.Close()
+			lines, err := logs.ReadRange(r, from, to)
+			if err != nil {
+				sugar.InternalError(c, err)
+				return
+			}
+			sugar.SuccessResponse(c, 200, lines)
 			return
 		}
-	} else {
-		jobDetail, err := awsSession.GetJobDetail(b.BatchID)
-		if err != nil {
-			sugar.InternalError(c, err)
+		logStream, err := service.GetDeploymentStream(ctx, *deployment)
+		if err == aws.ErrNotFound {
+			sugar.ErrResponse(c, 404, "Logs not found")
 			return
 		}
-		logStream, err = awsSession.GetJobStream(*jobDetail.Container.LogStreamName)
 		if err != nil {
 			sugar.InternalError(c, err)
 			return
 		}
+		serveLogRange(c, deploymentLogs, *logStream.LogStreamName, from, to)
+		return
 	}
 
-	log.Printf("opening log stream: %s", *logStream.LogStreamName)
-
-	lstream := awsSession.NewStream(*logStream)
-
-	go func() {
-		for !b.HasFinished() {
-			select {
-			case <-ctx.Done():
-				log.Printf("closing log stream: %s", *logStream.LogStreamName)
-				return
-			case <-refreshTicker.C:
-				err := refreshBatchJobEvents(b, db)
-				if err != nil {
-					break
-				}
-			}
-		}
-		lstream.Ended = true
-	}()
-
-	stream.Start(ctx, lstream, c, awsSession.Conf().LogGroup)
-}
-
-func streamDeploymentLogs(service deployment.Service, awsSession batch.Service, c *gin.Context, deployment *models.Deployment) {
-	ctx, cancel := WithClose(c)
-	defer cancel()
-
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
@@ -121,8 +161,18 @@ func streamDeploymentLogs(service deployment.Service, awsSession batch.Service,
 		return true
 	})
 
+	r, ok, err := deploymentLogReader(ctx, service, *deployment)
+	if ok {
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+		defer r.Close()
+		streamReader(ctx, c, r)
+		return
+	}
+
 	var logStream *cloudwatchlogs.LogStream
-	var err error
 
 	stream.StartWithContext(ctx, c, func(ctx context.Context, w io.Writer) bool {
 		logStream, err = service.GetDeploymentStream(ctx, *deployment)
@@ -150,24 +200,92 @@ func streamDeploymentLogs(service deployment.Service, awsSession batch.Service,
 
 	log.Printf("opening log stream: %s", *logStream.LogStreamName)
 
-	lstream := awsSession.NewStream(*logStream)
+	r, err = deploymentLogs.Follow(ctx, *logStream.LogStreamName, func() bool {
+		err := refreshDeploymentEvents(deployment, db)
+		return err != nil || deployment.HasFinished()
+	})
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+	defer r.Close()
+	streamReader(ctx, c, r)
+}
 
-	go func() {
-		for !deployment.HasFinished() {
-			select {
-			case <-ctx.Done():
-				return
-			case <-refreshTicker.C:
-				err := refreshDeploymentEvents(deployment, db)
-				if err != nil {
-					break
-				}
+// logRange parses the range of log lines requested, with the from and to
+// query parameters. A missing to means the end of the logs. It returns
+// false, having responded, for invalid ranges.
+func logRange(c *gin.Context) (from int, to int, isRange bool, ok bool) {
+	fromParam, hasFrom := c.GetQuery("from")
+	toParam, hasTo := c.GetQuery("to")
+	if !hasFrom && !hasTo {
+		return 0, -1, false, true
+	}
+
+	var err error
+	to = -1
+	if hasFrom {
+		from, err = strconv.Atoi(fromParam)
+		if err != nil || from < 0 {
+			sugar.ErrResponse(c, 400, "from must be a line number")
+			return 0, 0, true, false
+		}
+	}
+	if hasTo {
+		to, err = strconv.Atoi(toParam)
+		if err != nil || to < from {
+			sugar.ErrResponse(c, 400, "to must be a line number after from")
+			return 0, 0, true, false
+		}
+	}
+	return from, to, true, true
+}
+
+// serveLogRange responds with a range of the lines of the logs named name
+// in source.
+func serveLogRange(c *gin.Context, source logs.LogSource, name string, from int, to int) {
+	if name == "" {
+		sugar.ErrResponse(c, 404, "Logs not found")
+		return
+	}
+	lines, err := source.Range(c, name, from, to)
+	if err == logs.ErrNotFound {
+		sugar.ErrResponse(c, 404, "Logs not found")
+		return
+	}
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+	sugar.SuccessResponse(c, 200, lines)
+}
+
+// deploymentLogReader opens the logs of deployments which don't log to
+// CloudWatch, returning false for those that do.
+func deploymentLogReader(ctx context.Context, service deployment.Service, dep models.Deployment) (io.ReadCloser, bool, error) {
+	lr, ok := service.(deployment.LogReader)
+	if !ok {
+		return nil, false, nil
+	}
+	logs, err := lr.DeploymentLogs(ctx, dep)
+	if err == deployment.ErrNoLogStream {
+		return nil, false, nil
+	}
+	return logs, true, err
+}
+
+// streamReader streams r to the client until it ends.
+func streamReader(ctx context.Context, c *gin.Context, r io.Reader) {
+	buf := make([]byte, 32*1024)
+	stream.StartWithContext(ctx, c, func(ctx context.Context, w io.Writer) bool {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return false
 			}
 		}
-		lstream.Ended = true
-	}()
-	conf := service.GetServiceConfig()
-	stream.Start(ctx, lstream, c, conf.LogGroup)
+		return err == nil
+	})
 }
 
 func refreshBatchJobEvents(b *models.BatchJob, db *gorm.DB) error {
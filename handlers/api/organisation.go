@@ -0,0 +1,165 @@
+package api
+
+import (
+	"github.com/ReconfigureIO/platform/middleware"
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/sugar"
+	"github.com/gin-gonic/gin"
+)
+
+// Organisation handles requests for organisations and their members.
+type Organisation struct {
+	Repo models.OrganisationRepo
+}
+
+// byID gets an organisation the current user is a member of, 404 if it
+// doesn't exist.
+func (o Organisation) byID(c *gin.Context) (models.Organisation, error) {
+	var id string
+	if !bindID(c, &id) {
+		return models.Organisation{}, errNotFound
+	}
+	user := middleware.GetUser(c)
+	org, err := o.Repo.ByIDForUser(id, user.ID)
+	if err != nil {
+		sugar.NotFoundOrError(c, err)
+	}
+	return org, err
+}
+
+// List lists the current user's organisations.
+func (o Organisation) List(c *gin.Context) {
+	user := middleware.GetUser(c)
+	orgs, err := o.Repo.ListForUser(user.ID)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, orgs)
+}
+
+// Create creates an organisation owned by the current user.
+func (o Organisation) Create(c *gin.Context) {
+	post := models.PostOrganisation{}
+	c.BindJSON(&post)
+
+	if !sugar.ValidateRequest(c, post) {
+		return
+	}
+
+	user := middleware.GetUser(c)
+	org, err := o.Repo.Create(post.Name, user)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 201, org)
+}
+
+// Get fetches an organisation with its members.
+func (o Organisation) Get(c *gin.Context) {
+	org, err := o.byID(c)
+	if err != nil {
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, org)
+}
+
+// SetMember adds a user to an organisation, or changes their role. Only
+// owners can manage members.
+func (o Organisation) SetMember(c *gin.Context) {
+	org, err := o.byID(c)
+	if err != nil {
+		return
+	}
+
+	post := models.PostMembership{}
+	c.BindJSON(&post)
+
+	if !sugar.ValidateRequest(c, post) {
+		return
+	}
+	if !models.ValidRole(post.Role) {
+		sugar.ErrResponse(c, 400, "Role must be one of owner, member or viewer")
+		return
+	}
+
+	user := middleware.GetUser(c)
+	if !isOwner(org, user.ID) {
+		sugar.ErrResponse(c, 403, "Only owners can manage members")
+		return
+	}
+
+	member := models.User{}
+	err = db.First(&member, "email = ?", post.Email).Error
+	if err != nil {
+		sugar.NotFoundOrError(c, err)
+		return
+	}
+
+	if post.Role != models.RoleOwner && isOwner(org, member.ID) && countOwners(org) == 1 {
+		sugar.ErrResponse(c, 400, "An organisation must have an owner")
+		return
+	}
+
+	membership, err := o.Repo.SetMember(org.ID, member.ID, post.Role)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, membership)
+}
+
+// RemoveMember removes a user from an organisation. Owners can remove
+// anyone, and members can remove themselves.
+func (o Organisation) RemoveMember(c *gin.Context) {
+	org, err := o.byID(c)
+	if err != nil {
+		return
+	}
+
+	user := middleware.GetUser(c)
+	memberID := c.Param("user_id")
+	if memberID != user.ID && !isOwner(org, user.ID) {
+		sugar.ErrResponse(c, 403, "Only owners can manage members")
+		return
+	}
+	if isOwner(org, memberID) && countOwners(org) == 1 {
+		sugar.ErrResponse(c, 400, "An organisation must have an owner")
+		return
+	}
+
+	if _, err := o.Repo.Role(org.ID, memberID); err != nil {
+		sugar.NotFoundOrError(c, err)
+		return
+	}
+	if err := o.Repo.RemoveMember(org.ID, memberID); err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, nil)
+}
+
+func isOwner(org models.Organisation, userID string) bool {
+	for _, m := range org.Memberships {
+		if m.UserID == userID {
+			return m.Role == models.RoleOwner
+		}
+	}
+	return false
+}
+
+func countOwners(org models.Organisation) int {
+	n := 0
+	for _, m := range org.Memberships {
+		if m.Role == models.RoleOwner {
+			n++
+		}
+	}
+	return n
+}
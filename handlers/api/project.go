@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/ReconfigureIO/platform/middleware"
 	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/deployment"
 	"github.com/ReconfigureIO/platform/service/events"
 	"github.com/ReconfigureIO/platform/sugar"
 	"github.com/gin-gonic/gin"
@@ -13,17 +14,53 @@ import (
 type Project struct {
 	Events          events.EventService
 	PublicProjectID string
+	DeployService   deployment.Service
 }
 
 // PostProject is post request for new project.
 type PostProject struct {
-	Name string `json:"name" validate:"nonzero"`
+	Name              string  `json:"name" validate:"nonzero"`
+	OrganisationID    *string `json:"organisation_id"`
+	DeploymentBackend *string `json:"deployment_backend"`
 }
 
-// Query queries the db for current user's projects.
+// validBackend returns if the deployment backend of the request is
+// available, responding with an error if not.
+func (p Project) validBackend(c *gin.Context, post PostProject) bool {
+	if post.DeploymentBackend == nil || *post.DeploymentBackend == "" {
+		return true
+	}
+	if _, err := deployment.Resolve(p.DeployService, *post.DeploymentBackend); err != nil {
+		sugar.ErrResponse(c, 400, "Unknown deployment backend")
+		return false
+	}
+	return true
+}
+
+// canShareWith returns if the user may share projects with the
+// organisation of the request, responding with an error if not.
+func canShareWith(c *gin.Context, post PostProject) bool {
+	if post.OrganisationID == nil || *post.OrganisationID == "" {
+		return true
+	}
+	user := middleware.GetUser(c)
+	role, err := models.OrganisationDataSource(db).Role(*post.OrganisationID, user.ID)
+	if err == gorm.ErrRecordNotFound || (err == nil && !models.CanWrite(role)) {
+		sugar.ErrResponse(c, 403, "Not a member of this organisation")
+		return false
+	}
+	if err != nil {
+		sugar.InternalError(c, err)
+		return false
+	}
+	return true
+}
+
+// Query queries the db for current user's projects, including those
+// shared with them through an organisation.
 func (p Project) Query(c *gin.Context) *gorm.DB {
 	user := middleware.GetUser(c)
-	return db.Where("projects.user_id=?", user.ID)
+	return models.AccessibleBy(db, "projects.user_id", user.ID, accessRoles(c))
 }
 
 // ByID get the first build by ID, 404 if it doesn't exist
@@ -49,8 +86,17 @@ func (p Project) Create(c *gin.Context) {
 	if !sugar.ValidateRequest(c, post) {
 		return
 	}
+	if !canShareWith(c, post) || !p.validBackend(c, post) {
+		return
+	}
+	if post.OrganisationID != nil && *post.OrganisationID == "" {
+		post.OrganisationID = nil
+	}
 	user := middleware.GetUser(c)
-	newProject := models.Project{UserID: user.ID, Name: post.Name}
+	newProject := models.Project{UserID: user.ID, Name: post.Name, OrganisationID: post.OrganisationID}
+	if post.DeploymentBackend != nil {
+		newProject.DeploymentBackend = *post.DeploymentBackend
+	}
 	if err := db.Create(&newProject).Error; err != nil {
 		sugar.InternalError(c, err)
 		return
@@ -75,10 +121,46 @@ func (p Project) Update(c *gin.Context) {
 		return
 	}
 
-	db.Model(&project).Updates(post)
+	// only the owner decides who a project is shared with
+	user := middleware.GetUser(c)
+	if post.OrganisationID != nil && project.UserID != user.ID {
+		sugar.ErrResponse(c, 403, "Only the owner can change a project's organisation")
+		return
+	}
+	if !canShareWith(c, post) || !p.validBackend(c, post) {
+		return
+	}
+
+	updates := map[string]interface{}{"name": post.Name}
+	if post.DeploymentBackend != nil {
+		updates["deployment_backend"] = *post.DeploymentBackend
+	}
+	if post.OrganisationID != nil {
+		if *post.OrganisationID == "" {
+			post.OrganisationID = nil
+		}
+		updates["organisation_id"] = post.OrganisationID
+	}
+	db.Model(&project).Updates(updates)
 	sugar.SuccessResponse(c, 200, project)
 }
 
+// Reports returns the utilisation of each of a project's builds over time.
+func (p Project) Reports(c *gin.Context) {
+	project, err := p.ByID(c)
+	if err != nil {
+		return
+	}
+
+	points, err := models.BuildDataSource(db).ProjectUtilisation(project.ID)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, points)
+}
+
 // List lists all projects.
 func (p Project) List(c *gin.Context) {
 	projects := []models.Project{}
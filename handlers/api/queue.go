@@ -0,0 +1,179 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ReconfigureIO/platform/middleware"
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/queue"
+	"github.com/ReconfigureIO/platform/sugar"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// deploymentHistory is how far back deployments are looked at to estimate
+// how long queued deployments will wait.
+const deploymentHistory = 30 * 24 * time.Hour
+
+// userPlan returns the identifier of user's subscription plan, which
+// their jobs' queue weights and limits depend on. If it can't be looked
+// up, the open source plan is assumed, so the user is given no more than
+// any plan allows.
+func userPlan(user models.User) string {
+	sub, err := models.SubscriptionDataSource(db).CurrentSubscription(user)
+	if err != nil {
+		log.WithError(err).WithField("user", user.ID).Error("Unable to get subscription plan")
+		return models.PlanOpenSource
+	}
+	return sub.Identifier
+}
+
+// pushJob pushes a job onto q for user, unless they already have as many
+// jobs waiting as their plan allows. prepare is called before the job is
+// pushed. Errors are written to the response, with false returned.
+func pushJob(c *gin.Context, q queue.Queue, kind string, id string, user models.User, prepare func() error) bool {
+	plan := userPlan(user)
+	waiting, err := q.CountUserJobsInStatus(user, models.StatusQueued)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return false
+	}
+	if limit := models.PlanQueueLimit(plan); waiting >= limit {
+		sugar.ErrResponse(c, http.StatusServiceUnavailable, fmt.Sprintf("Exceeded queued %s max of %d", kind, limit))
+		return false
+	}
+
+	if err := prepare(); err != nil {
+		sugar.InternalError(c, err)
+		return false
+	}
+	q.Push(queue.Job{
+		ID:     id,
+		Weight: models.PlanWeight(plan),
+		User:   user,
+	})
+	return true
+}
+
+// Queue handles requests for the state of the deployment queue.
+type Queue struct{}
+
+// QueueStatus is the state of the deployment queue as a user sees it.
+type QueueStatus struct {
+	Queued      int                    `json:"queued"`
+	Dispatched  int                    `json:"dispatched"`
+	Concurrency int                    `json:"concurrency"`
+	Deployments []models.QueuePosition `json:"deployments"`
+}
+
+// deploymentDurationTTL is how long the typical deployment duration is
+// cached for. It changes slowly, and is costly to work out.
+const deploymentDurationTTL = time.Hour
+
+// deploymentDuration caches the typical deployment duration.
+var deploymentDuration struct {
+	sync.Mutex
+	value   time.Duration
+	expires time.Time
+}
+
+// typicalDeploymentDuration returns how long deployments have run for
+// recently, as of now.
+func typicalDeploymentDuration(now time.Time) (time.Duration, error) {
+	deploymentDuration.Lock()
+	defer deploymentDuration.Unlock()
+	if now.Before(deploymentDuration.expires) {
+		return deploymentDuration.value, nil
+	}
+
+	runTime, err := models.TypicalDeploymentDuration(models.DeploymentDataSource(db), now.Add(-deploymentHistory))
+	if err != nil {
+		return 0, err
+	}
+	deploymentDuration.value = runTime
+	deploymentDuration.expires = now.Add(deploymentDurationTTL)
+	return runTime, nil
+}
+
+// runningDeploymentStatuses are the statuses of deployments with an
+// instance, which take up a slot whether or not they were queued.
+var runningDeploymentStatuses = []string{
+	models.StatusQueued,
+	models.StatusStarted,
+	models.StatusTerminating,
+}
+
+// maxRunningDeployments is the most running deployments looked at to
+// estimate when queued deployments start.
+const maxRunningDeployments = 1000
+
+// runningDeploymentStarts returns when each running deployment started,
+// or was launched if its instance hasn't started yet.
+func runningDeploymentStarts() ([]time.Time, error) {
+	deps, err := models.DeploymentDataSource(db).GetWithStatus(runningDeploymentStatuses, maxRunningDeployments)
+	if err != nil {
+		return nil, err
+	}
+	starts := make([]time.Time, 0, len(deps))
+	for _, dep := range deps {
+		start := dep.StartTime()
+		if start.IsZero() && len(dep.Events) > 0 {
+			start = dep.Events[0].Timestamp
+		}
+		starts = append(starts, start)
+	}
+	return starts, nil
+}
+
+// deploymentQueueStatus returns the state of the deployment queue,
+// estimating start times from the deployments running, and how long
+// deployments have run recently.
+func deploymentQueueStatus() (queue.Status, error) {
+	now := time.Now()
+	runTime, err := typicalDeploymentDuration(now)
+	if err != nil {
+		return queue.Status{}, err
+	}
+	status, err := deploymentQueue.Status(runTime)
+	if err != nil {
+		return status, err
+	}
+	running, err := runningDeploymentStarts()
+	if err != nil {
+		return status, err
+	}
+	status.Estimate(now, running, runTime)
+	return status, nil
+}
+
+// Get shows how busy the deployment queue is, and where the user's queued
+// deployments are in it.
+func (q Queue) Get(c *gin.Context) {
+	if deploymentQueue == nil {
+		sugar.ErrResponse(c, http.StatusNotFound, "Deployment queue is not enabled")
+		return
+	}
+	user := middleware.GetUser(c)
+
+	status, err := deploymentQueueStatus()
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	resp := QueueStatus{
+		Queued:      len(status.Queued),
+		Dispatched:  status.Dispatched,
+		Concurrency: status.Concurrency,
+		Deployments: []models.QueuePosition{},
+	}
+	for _, p := range status.Queued {
+		if p.UserID == user.ID {
+			resp.Deployments = append(resp.Deployments, p)
+		}
+	}
+	sugar.SuccessResponse(c, 200, resp)
+}
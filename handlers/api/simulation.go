@@ -32,14 +32,17 @@ func (s Simulation) Preload(db *gorm.DB) *gorm.DB {
 		Preload("BatchJob").
 		Preload("BatchJob.Events", func(db *gorm.DB) *gorm.DB {
 			return db.Order("timestamp ASC")
+		}).
+		Preload("BatchJob.Attempts", func(db *gorm.DB) *gorm.DB {
+			return db.Order("attempt ASC")
 		})
 }
 
 // Query fetches simulations for user and project.
 func (s Simulation) Query(c *gin.Context) *gorm.DB {
 	user := middleware.GetUser(c)
-	joined := db.Joins("join projects on projects.id = simulations.project_id").
-		Where("projects.user_id=?", user.ID)
+	joined := models.AccessibleBy(db.Joins("join projects on projects.id = simulations.project_id"),
+		"projects.user_id", user.ID, accessRoles(c))
 	return s.Preload(joined)
 }
 
@@ -109,13 +112,123 @@ func (s Simulation) Input(c *gin.Context) {
 		return
 	}
 
-	key := fmt.Sprintf("simulation/%s/simulation.tar.gz", sim.ID)
+	_, err = s.Storage.Upload(sim.InputUrl(), c.Request.Body)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	s.start(c, sim)
+}
+
+// InputURL hands out a URL to upload a simulation's input to directly,
+// instead of through Input. Once uploaded, InputComplete starts the
+// simulation.
+func (s Simulation) InputURL(c *gin.Context) {
+	sim, err := s.ByID(c)
+	if err != nil {
+		return
+	}
+
+	if sim.Status() != models.StatusSubmitted {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Simulation is '%s', not SUBMITTED", sim.Status()))
+		return
+	}
+
+	presignInput(c, s.Storage, sim.InputUrl())
+}
+
+// InputComplete starts a simulation whose input was uploaded to a URL from
+// InputURL.
+func (s Simulation) InputComplete(c *gin.Context) {
+	sim, err := s.ByID(c)
+	if err != nil {
+		return
+	}
+
+	if sim.Status() != models.StatusSubmitted {
+		sugar.ErrResponse(c, 400, fmt.Sprintf("Simulation is '%s', not SUBMITTED", sim.Status()))
+		return
+	}
+
+	if !inputUploaded(c, s.Storage, sim.InputUrl(), "Simulation") {
+		return
+	}
+
+	s.start(c, sim)
+}
+
+// Rerun starts a new simulation of an existing simulation's input, which
+// is copied in storage rather than uploaded again.
+func (s Simulation) Rerun(c *gin.Context) {
+	// the body is optional
+	post := models.PostRerun{}
+	if c.Request.ContentLength != 0 {
+		c.BindJSON(&post)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	original, err := s.ByID(c)
+	if err != nil {
+		return
+	}
+	if original.Status() == models.StatusSubmitted {
+		sugar.ErrResponse(c, 400, "Simulation has no input to rerun")
+		return
+	}
+	exists, err := inputExists(s.Storage, original.InputUrl())
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+	if !exists {
+		sugar.ErrResponse(c, 400, "Simulation input is no longer available")
+		return
+	}
 
-	s3Url, err := s.Storage.Upload(key, c.Request.Body)
+	command := original.Command
+	if post.Command != nil {
+		command = *post.Command
+	}
+	if command == "" {
+		sugar.ErrResponse(c, 400, "Simulation command can't be empty")
+		return
+	}
+	newSim := models.Simulation{Project: original.Project, Command: command, Token: uniuri.NewLen(64)}
+	err = db.Create(&newSim).Error
 	if err != nil {
 		sugar.InternalError(c, err)
 		return
 	}
+	// the simulation's input is keyed by its ID, so it's copied once
+	// created, and the simulation removed if it can't be
+	err = s.Storage.Copy(original.InputUrl(), newSim.InputUrl())
+	if err != nil {
+		db.Delete(&newSim)
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.EnqueueEvent(s.Events, c, "Posted Simulation", newSim.Project.UserID, map[string]interface{}{"simulation_id": newSim.ID, "project_name": newSim.Project.Name, "rerun_of": original.ID})
+	s.start(c, newSim)
+}
+
+// start runs the batch job of a simulation whose input has been uploaded,
+// or queues it to be run.
+func (s Simulation) start(c *gin.Context, sim models.Simulation) {
+	if simulationQueue != nil {
+		user := middleware.GetUser(c)
+		queued := pushJob(c, simulationQueue, "simulation", sim.ID, user, func() error {
+			batchJob := BatchService{AWS: s.AWS}.New("")
+			return db.Model(&sim).Association("BatchJob").Append(batchJob).Error
+		})
+		if queued {
+			sugar.SuccessResponse(c, 200, sim)
+		}
+		return
+	}
 
 	urlEvents := s.APIBaseURL
 	urlEvents.RawQuery = fmt.Sprintf("token=%s", sim.Token)
@@ -125,7 +238,7 @@ func (s Simulation) Input(c *gin.Context) {
 	urlReports.RawQuery = fmt.Sprintf("token=%s", sim.Token)
 	urlReports.Path = "/simulations/" + sim.ID + "/reports"
 
-	simID, err := s.AWS.RunSimulation(s3Url, urlEvents.String(), urlReports.String(), sim.Command)
+	simID, err := s.AWS.RunSimulation(sim, urlEvents.String(), urlReports.String())
 	if err != nil {
 		sugar.InternalError(c, err)
 		return
@@ -172,6 +285,31 @@ func (s Simulation) Get(c *gin.Context) {
 	sugar.SuccessResponse(c, 200, sim)
 }
 
+// Delete cancels a simulation, halting its batch job.
+func (s Simulation) Delete(c *gin.Context) {
+	sim, err := s.ByID(c)
+	if err != nil {
+		return
+	}
+
+	cancelled, ok := (BatchService{AWS: s.AWS}).Cancel(c, &sim.BatchJob, "Simulation")
+	if !ok {
+		return
+	}
+	if simulationQueue != nil {
+		err = simulationQueue.Remove(sim.ID)
+		if err != nil {
+			sugar.InternalError(c, err)
+			return
+		}
+	}
+
+	if cancelled {
+		sugar.EnqueueEvent(s.Events, c, "Cancelled Simulation", sim.Project.UserID, map[string]interface{}{"simulation_id": sim.ID, "project_name": sim.Project.Name})
+	}
+	sugar.SuccessResponse(c, 200, sim)
+}
+
 // Logs stream logs for simulation.
 func (s Simulation) Logs(c *gin.Context) {
 	sim, err := s.ByID(c)
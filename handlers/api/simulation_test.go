@@ -19,8 +19,9 @@ func TestServiceInterface(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	s := batch.NewMockService(mockCtrl)
-	s.EXPECT().RunSimulation("foo", "bar", "baz", "test").Return("foobar", nil)
-	ss, err := s.RunSimulation("foo", "bar", "baz", "test")
+	sim := models.Simulation{ID: "foo", Command: "test"}
+	s.EXPECT().RunSimulation(sim, "bar", "baz").Return("foobar", nil)
+	ss, err := s.RunSimulation(sim, "bar", "baz")
 	if err != nil || ss != "foobar" {
 		t.Error("unexpected result")
 	}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/notify"
+	"github.com/ReconfigureIO/platform/service/stream"
+	"github.com/ReconfigureIO/platform/sugar"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	keepAliveInterval = 15 * time.Second
+	// fallbackInterval is how often to check for new events when there is
+	// no notification hub.
+	fallbackInterval = 5 * time.Second
+)
+
+var statusHub *notify.Hub
+
+// StatusHub sets the hub notifying of new job events.
+func StatusHub(h *notify.Hub) {
+	statusHub = h
+}
+
+// statusEvents returns the events stored so far, and if the last one is
+// final.
+type statusEvents func() ([]interface{}, bool, error)
+
+// streamStatus sends an SSE status event for each event returned by
+// refresh, checking again whenever channel is notified with key, until a
+// final event is sent or the client goes away.
+func streamStatus(c *gin.Context, channel string, key string, refresh statusEvents) {
+	var notified <-chan struct{}
+	if statusHub != nil {
+		sub := statusHub.Subscribe(channel, key)
+		defer sub.Close()
+		notified = sub.C
+	} else {
+		ticker := time.NewTicker(fallbackInterval)
+		defer ticker.Stop()
+		ticks := make(chan struct{})
+		go func() {
+			for range ticker.C {
+				select {
+				case ticks <- struct{}{}:
+				case <-c.Request.Context().Done():
+					return
+				}
+			}
+		}()
+		notified = ticks
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	sent := 0
+	send := func() bool {
+		events, finished, err := refresh()
+		if err != nil {
+			c.Error(err)
+			return false
+		}
+		for _, event := range events[sent:] {
+			c.SSEvent("status", event)
+		}
+		sent = len(events)
+		return !finished
+	}
+
+	ctx := c.Request.Context()
+	first := true
+	stream.StartWithContext(ctx, c, func(ctx context.Context, w io.Writer) bool {
+		if first {
+			first = false
+			return send()
+		}
+
+		keepAlive := time.NewTimer(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-notified:
+			return send()
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
+
+// streamBatchJobStatus streams the events of a batch job.
+func streamBatchJobStatus(c *gin.Context, batchJob *models.BatchJob) {
+	if batchJob.ID == 0 {
+		sugar.ErrResponse(c, 400, "No job has been started yet")
+		return
+	}
+	streamStatus(c, notify.BatchJobEvents, strconv.FormatInt(batchJob.ID, 10), func() ([]interface{}, bool, error) {
+		if err := refreshBatchJobEvents(batchJob, db); err != nil {
+			return nil, false, err
+		}
+		events := make([]interface{}, len(batchJob.Events))
+		for i, e := range batchJob.Events {
+			events[i] = e
+		}
+		return events, batchJob.HasFinished(), nil
+	})
+}
+
+// StatusStream streams a build's status changes as server-sent events.
+func (b Build) StatusStream(c *gin.Context) {
+	build, err := b.ByID(c)
+	if err != nil {
+		return
+	}
+	streamBatchJobStatus(c, &build.BatchJob)
+}
+
+// StatusStream streams a simulation's status changes as server-sent events.
+func (s Simulation) StatusStream(c *gin.Context) {
+	sim, err := s.ByID(c)
+	if err != nil {
+		return
+	}
+	streamBatchJobStatus(c, &sim.BatchJob)
+}
+
+// StatusStream streams a graph's status changes as server-sent events.
+func (g Graph) StatusStream(c *gin.Context) {
+	graph, err := g.ByID(c)
+	if err != nil {
+		return
+	}
+	streamBatchJobStatus(c, &graph.BatchJob)
+}
+
+// StatusStream streams a deployment's status changes as server-sent events.
+func (d Deployment) StatusStream(c *gin.Context) {
+	dep, err := d.ByID(c)
+	if err != nil {
+		return
+	}
+	streamStatus(c, notify.DeploymentEvents, dep.ID, func() ([]interface{}, bool, error) {
+		if err := refreshDeploymentEvents(&dep, db); err != nil {
+			return nil, false, err
+		}
+		events := make([]interface{}, len(dep.Events))
+		for i, e := range dep.Events {
+			events[i] = e
+		}
+		return events, dep.HasFinished(), nil
+	})
+}
@@ -0,0 +1,15 @@
+package api
+
+import (
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/sugar"
+	"github.com/gin-gonic/gin"
+)
+
+// Target handles requests for the FPGA platforms builds can target.
+type Target struct{}
+
+// List lists the supported targets.
+func (t Target) List(c *gin.Context) {
+	sugar.SuccessResponse(c, 200, models.Targets)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ReconfigureIO/platform/middleware"
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/sugar"
+	"github.com/gin-gonic/gin"
+)
+
+// APIToken handles requests for a user's API tokens.
+type APIToken struct {
+	Repo models.APITokenRepo
+}
+
+// NewAPIToken is the response to creating an API token. The login token is
+// only ever shown once.
+type NewAPIToken struct {
+	models.APIToken
+	LoginToken string `json:"login_token"`
+}
+
+// List lists the current user's API tokens.
+func (t APIToken) List(c *gin.Context) {
+	user := middleware.GetUser(c)
+	tokens, err := t.Repo.ListForUser(user.ID)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, tokens)
+}
+
+// Create creates an API token.
+func (t APIToken) Create(c *gin.Context) {
+	post := models.PostAPIToken{}
+	c.BindJSON(&post)
+
+	if !sugar.ValidateRequest(c, post) {
+		return
+	}
+
+	// A token can't be used to create a more powerful one
+	current, usingToken := middleware.GetAPIToken(c)
+	for _, scope := range post.Scopes {
+		if !models.ValidScope(scope) {
+			sugar.ErrResponse(c, 400, fmt.Sprintf("Invalid scope %s", scope))
+			return
+		}
+		parts := strings.Split(scope, ":")
+		if usingToken && !current.Allows(parts[0], parts[1]) {
+			sugar.ErrResponse(c, 403, fmt.Sprintf("Scope %s exceeds the scopes of the current token", scope))
+			return
+		}
+	}
+
+	if post.ExpiresAt != nil && !post.ExpiresAt.After(time.Now()) {
+		sugar.ErrResponse(c, 400, "Expiry must be in the future")
+		return
+	}
+
+	user := middleware.GetUser(c)
+	token, secret, err := t.Repo.Create(user.ID, post)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 201, NewAPIToken{APIToken: token, LoginToken: token.LoginToken(secret)})
+}
+
+// Delete revokes an API token.
+func (t APIToken) Delete(c *gin.Context) {
+	var id string
+	if !bindID(c, &id) {
+		return
+	}
+	user := middleware.GetUser(c)
+	token, err := t.Repo.Revoke(user.ID, id)
+	if err != nil {
+		sugar.NotFoundOrError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, token)
+}
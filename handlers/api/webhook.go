@@ -0,0 +1,104 @@
+package api
+
+import (
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/webhooks"
+	"github.com/ReconfigureIO/platform/sugar"
+	"github.com/gin-gonic/gin"
+)
+
+const maxDeliveries = 100
+
+// Webhook handles requests for a project's webhooks.
+type Webhook struct{}
+
+// project gets the project of the request, 404 if the user can't access it.
+func (w Webhook) project(c *gin.Context) (models.Project, error) {
+	return Project{}.ByID(c)
+}
+
+// List lists a project's webhooks.
+func (w Webhook) List(c *gin.Context) {
+	project, err := w.project(c)
+	if err != nil {
+		return
+	}
+
+	hooks, err := models.WebhookDataSource(db).ListForProject(project.ID)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, hooks)
+}
+
+// Create subscribes a webhook to a project's events.
+func (w Webhook) Create(c *gin.Context) {
+	project, err := w.project(c)
+	if err != nil {
+		return
+	}
+
+	post := models.PostWebhook{}
+	c.BindJSON(&post)
+
+	if !sugar.ValidateRequest(c, post) {
+		return
+	}
+
+	// webhooks are delivered from inside our network, so they can't
+	// point back into it
+	err = webhooks.CheckURL(c.Request.Context(), post.URL)
+	if err != nil {
+		sugar.ErrResponse(c, 400, err)
+		return
+	}
+
+	hook, err := models.WebhookDataSource(db).Create(project.ID, post)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 201, models.NewWebhook{Webhook: hook, Secret: hook.Secret})
+}
+
+// Delete unsubscribes a webhook.
+func (w Webhook) Delete(c *gin.Context) {
+	project, err := w.project(c)
+	if err != nil {
+		return
+	}
+
+	err = models.WebhookDataSource(db).Delete(project.ID, c.Param("webhook_id"))
+	if err != nil {
+		sugar.NotFoundOrError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, nil)
+}
+
+// Deliveries lists the most recent deliveries of a webhook.
+func (w Webhook) Deliveries(c *gin.Context) {
+	project, err := w.project(c)
+	if err != nil {
+		return
+	}
+
+	hook := models.Webhook{}
+	err = db.Where("project_id = ?", project.ID).First(&hook, "id = ?", c.Param("webhook_id")).Error
+	if err != nil {
+		sugar.NotFoundOrError(c, err)
+		return
+	}
+
+	deliveries, err := models.WebhookDataSource(db).ListDeliveries(hook.ID, maxDeliveries)
+	if err != nil {
+		sugar.InternalError(c, err)
+		return
+	}
+
+	sugar.SuccessResponse(c, 200, deliveries)
+}
@@ -12,14 +12,18 @@ import (
 	"github.com/ReconfigureIO/platform/routes"
 	"github.com/ReconfigureIO/platform/service/auth"
 	"github.com/ReconfigureIO/platform/service/auth/github"
-	"github.com/ReconfigureIO/platform/service/aws"
+	"github.com/ReconfigureIO/platform/service/batch"
+	"github.com/ReconfigureIO/platform/service/batchretry"
 	"github.com/ReconfigureIO/platform/service/deployment"
 	"github.com/ReconfigureIO/platform/service/events"
 	"github.com/ReconfigureIO/platform/service/leads"
+	"github.com/ReconfigureIO/platform/service/logs"
+	"github.com/ReconfigureIO/platform/service/notify"
 	"github.com/ReconfigureIO/platform/service/queue"
 	s3reco "github.com/ReconfigureIO/platform/service/storage/s3"
 	awsaws "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	s3aws "github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/gin-contrib/cors"
@@ -33,22 +37,50 @@ var (
 	version string
 )
 
-func startDeploymentQueue(conf config.Config, db *gorm.DB) queue.Queue {
+func startDeploymentQueue(conf config.Config, db *gorm.DB, deploy deployment.Service, callbackProtocol string, hub *notify.Hub) queue.Queue {
 	runner := queue.DeploymentRunner{
 		Hostname: conf.Host,
+		Scheme:   callbackProtocol,
 		DB:       db,
-		Service:  deployment.New(conf.Reco.Deploy),
+		Service:  deploy,
 	}
-	deploymentQueue := queue.NewWithDBStore(
+	deploymentQueue := queue.NewFromConfig(
 		db,
 		runner,
-		2, // TODO make this non static.
-		"deployment",
+		queue.TypeDeployment,
+		conf.Reco.Queue,
+		hub,
 	)
 	go deploymentQueue.Start()
 	return deploymentQueue
 }
 
+// startBatchQueues starts the queues of builds, simulations and graphs, in
+// that order.
+func startBatchQueues(conf config.Config, db *gorm.DB, batchService batch.Service, apiBaseURL url.URL, hub *notify.Hub) []queue.Queue {
+	var queues []queue.Queue
+	for _, jobType := range []string{queue.TypeBuild, queue.TypeSimulation, queue.TypeGraph} {
+		runner := queue.BatchRunner{
+			Type:       jobType,
+			APIBaseURL: apiBaseURL,
+			Batch:      batchService,
+			DB:         db,
+		}
+		runner.Retrier = &batchretry.Retrier{
+			Backend:   batchService.Backend(),
+			BatchRepo: models.BatchDataSource(db),
+			Resubmit: func(kind string, id string) (string, error) {
+				return runner.Resubmit(id)
+			},
+			MaxAttempts: conf.Reco.Retry.MaxAttempts,
+		}
+		q := queue.NewFromConfig(db, runner, jobType, conf.Reco.Queue, hub)
+		go q.Start()
+		queues = append(queues, q)
+	}
+	return queues
+}
+
 func main() {
 	log.Info("Parsing Config")
 	conf, err := config.ParseEnvConfig()
@@ -97,9 +129,19 @@ func main() {
 		S3API:       s3aws.New(session),
 	}
 
-	awsSession := aws.New(conf.Reco.AWS)
+	batchService, err := batch.NewFromConfig(conf.Reco.Batch, conf.Reco.AWS)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	deploy := deployment.New(conf.Reco.Deploy)
+	deploy, err := deployment.NewFromConfig(conf.Reco.Deploy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	deploymentLogs := &logs.CloudWatch{
+		CloudWatchLogsAPI: cloudwatchlogs.New(session, awsaws.NewConfig().WithRegion(conf.Reco.Deploy.HomeRegion()).WithEndpoint(conf.Reco.AWS.EndPoint)),
+		LogGroup:          conf.Reco.Deploy.LogGroup,
+	}
 
 	publicProjectID := conf.Reco.PublicProjectID
 
@@ -158,7 +200,8 @@ func main() {
     APIBaseURL,
 		r,
 		db,
-		awsSession,
+		batchService,
+		deploymentLogs,
 		events,
 		leads,
 		storageService,
@@ -170,14 +213,29 @@ func main() {
 		models.BatchDataSource(db),
 	)
 
+	// job status notifications
+	hub, err := notify.New(conf.DbUrl, notify.BatchJobEvents, notify.DeploymentEvents, notify.QueueEntries)
+	if err != nil {
+		log.WithError(err).Error("Couldn't listen for job events, status streams and queues will poll")
+	} else {
+		api.StatusHub(hub)
+	}
+
 	// queue
 	var deploymentQueue queue.Queue
 	if conf.Reco.FeatureDepQueue {
 		log.Info("deployment queue enabled. starting...")
-		deploymentQueue = startDeploymentQueue(*conf, db)
+		deploymentQueue = startDeploymentQueue(*conf, db, deploy, callbackProtocol, hub)
 		api.DepQueue(deploymentQueue)
 		log.Info("deployment queue started.")
 	}
+	var batchQueues []queue.Queue
+	if conf.Reco.FeatureBatchQueue {
+		log.Info("build, simulation and graph queues enabled. starting...")
+		batchQueues = startBatchQueues(*conf, db, batchService, APIBaseURL, hub)
+		api.BatchQueues(batchQueues[0], batchQueues[1], batchQueues[2])
+		log.Info("build, simulation and graph queues started.")
+	}
 
 	// Listen and Server in 0.0.0.0:$PORT
 	err = r.Run(":" + conf.Port)
@@ -187,5 +245,8 @@ func main() {
 		if deploymentQueue != nil {
 			deploymentQueue.Halt()
 		}
+		for _, q := range batchQueues {
+			q.Halt()
+		}
 	}
 }
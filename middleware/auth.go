@@ -14,8 +14,9 @@ import (
 )
 
 const (
-	strUserID = "user_id"
-	strUser   = "reco_user"
+	strUserID   = "user_id"
+	strUser     = "reco_user"
+	strAPIToken = "reco_api_token"
 )
 
 // SessionAuth handles session authentication.
@@ -50,6 +51,11 @@ func TokenAuth(db *gorm.DB, events events.EventService, config config.RecoConfig
 			return
 		}
 
+		if strings.HasPrefix(username, models.APITokenPrefix) {
+			apiTokenAuth(c, db, events, strings.TrimPrefix(username, models.APITokenPrefix), pass)
+			return
+		}
+
 		var prefix string
 		if config.Env == "development-on-prem" {
 			prefix = "onprem_"
@@ -88,6 +94,60 @@ func TokenAuth(db *gorm.DB, events events.EventService, config config.RecoConfig
 	}
 }
 
+// apiTokenAuth authenticates with the credentials of an API token.
+func apiTokenAuth(c *gin.Context, db *gorm.DB, events events.EventService, tokenID string, secret string) {
+	token, err := models.APITokenDataSource(db).Authenticate(tokenID, secret)
+
+	if err == models.ErrAPITokenInvalid {
+		// Credentials doesn't match, we return 401 and abort handlers chain.
+		c.Header("WWW-Authenticate", "Authorization Required")
+		c.AbortWithStatus(401)
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatus(500)
+		return
+	}
+
+	c.Set(strUser, token.User)
+	c.Set(strAPIToken, token)
+	events.Seen(token.User)
+}
+
+// RequiresScope exits with a 403 if the request was authenticated with an
+// API token which doesn't grant access to resource. Safe methods need read
+// access, everything else needs write access.
+func RequiresScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := GetAPIToken(c)
+		if !ok {
+			return
+		}
+
+		access := models.AccessWrite
+		switch c.Request.Method {
+		case "GET", "HEAD", "OPTIONS":
+			access = models.AccessRead
+		}
+
+		if !token.Allows(resource, access) {
+			c.AbortWithStatus(403)
+		}
+	}
+}
+
+// GetAPIToken gets the API token the request was authenticated with, if any.
+func GetAPIToken(c *gin.Context) (models.APIToken, bool) {
+	token := models.APIToken{}
+	t, exists := c.Get(strAPIToken)
+	if exists {
+		token = t.(models.APIToken)
+	}
+	return token, exists
+}
+
 // RequiresUser exits with a 403 if the user doesn't exist
 func RequiresUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -11,6 +11,18 @@ import (
 	"github.com/ReconfigureIO/platform/migration/migration201802231224"
 	"github.com/ReconfigureIO/platform/migration/migration201807191024"
 	"github.com/ReconfigureIO/platform/migration/migration201809061242"
+	"github.com/ReconfigureIO/platform/migration/migration201810011200"
+	"github.com/ReconfigureIO/platform/migration/migration201810081200"
+	"github.com/ReconfigureIO/platform/migration/migration201810151200"
+	"github.com/ReconfigureIO/platform/migration/migration201810221200"
+	"github.com/ReconfigureIO/platform/migration/migration201810291200"
+	"github.com/ReconfigureIO/platform/migration/migration201811051200"
+	"github.com/ReconfigureIO/platform/migration/migration201811121200"
+	"github.com/ReconfigureIO/platform/migration/migration201811191200"
+	"github.com/ReconfigureIO/platform/migration/migration201811261200"
+	"github.com/ReconfigureIO/platform/migration/migration201811261300"
+	"github.com/ReconfigureIO/platform/migration/migration201812031200"
+	"github.com/ReconfigureIO/platform/migration/migration201812101200"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
@@ -26,6 +38,18 @@ var migrations = []*gormigrate.Migration{
 	&migration201802231224.Migration,
 	&migration201807191024.Migration,
 	&migration201809061242.Migration,
+	&migration201810011200.Migration,
+	&migration201810081200.Migration,
+	&migration201810151200.Migration,
+	&migration201810221200.Migration,
+	&migration201810291200.Migration,
+	&migration201811051200.Migration,
+	&migration201811121200.Migration,
+	&migration201811191200.Migration,
+	&migration201811261200.Migration,
+	&migration201811261300.Migration,
+	&migration201812031200.Migration,
+	&migration201812101200.Migration,
 }
 
 // MigrateSchema performs database migration.
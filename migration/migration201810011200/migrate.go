@@ -0,0 +1,37 @@
+package migration201810011200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201810011200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlCreateAPITokens).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding API tokens table to DB")
+	},
+}
+
+const (
+	sqlCreateAPITokens = `
+CREATE TABLE api_tokens (
+    id text PRIMARY KEY,
+    user_id text NOT NULL,
+    name text,
+    hash text NOT NULL,
+    scopes text NOT NULL DEFAULT '',
+    created_at timestamp with time zone,
+    expires_at timestamp with time zone,
+    revoked_at timestamp with time zone,
+    last_used_at timestamp with time zone
+);
+CREATE INDEX idx_api_tokens_user_id ON api_tokens (user_id);
+`
+)
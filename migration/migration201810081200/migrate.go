@@ -0,0 +1,41 @@
+package migration201810081200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201810081200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlCreateOrganisations).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding organisations to DB")
+	},
+}
+
+const (
+	sqlCreateOrganisations = `
+CREATE TABLE organisations (
+    id text PRIMARY KEY,
+    name text,
+    created_at timestamp with time zone
+);
+CREATE TABLE memberships (
+    id text PRIMARY KEY,
+    organisation_id text NOT NULL,
+    user_id text NOT NULL,
+    role text NOT NULL,
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX idx_memberships_organisation_user ON memberships (organisation_id, user_id);
+CREATE INDEX idx_memberships_user_id ON memberships (user_id);
+ALTER TABLE projects
+ADD COLUMN organisation_id text;
+`
+)
@@ -0,0 +1,49 @@
+package migration201810151200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201810151200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlCreateWebhooks).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding webhooks to DB")
+	},
+}
+
+const (
+	sqlCreateWebhooks = `
+CREATE TABLE webhooks (
+    id text PRIMARY KEY,
+    project_id text NOT NULL,
+    url text,
+    secret text,
+    event_types text,
+    created_at timestamp with time zone
+);
+CREATE INDEX idx_webhooks_project_id ON webhooks (project_id);
+CREATE TABLE webhook_deliveries (
+    id text PRIMARY KEY,
+    webhook_id text NOT NULL,
+    event_type text,
+    payload text,
+    status text,
+    attempts integer DEFAULT 0,
+    response_code integer DEFAULT 0,
+    error text,
+    next_attempt_at timestamp with time zone,
+    delivered_at timestamp with time zone,
+    created_at timestamp with time zone
+);
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+`
+)
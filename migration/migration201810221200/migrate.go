@@ -0,0 +1,47 @@
+package migration201810221200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201810221200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlNotifyEvents).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding event notification triggers to DB")
+	},
+}
+
+// Notify listeners of new job events, with the ID of the job as payload.
+const (
+	sqlNotifyEvents = `
+CREATE OR REPLACE FUNCTION notify_batch_job_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('batch_job_events', NEW.batch_job_id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER batch_job_events_notify
+AFTER INSERT ON batch_job_events
+FOR EACH ROW EXECUTE PROCEDURE notify_batch_job_event();
+
+CREATE OR REPLACE FUNCTION notify_deployment_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('deployment_events', NEW.deployment_id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER deployment_events_notify
+AFTER INSERT ON deployment_events
+FOR EACH ROW EXECUTE PROCEDURE notify_deployment_event();
+`
+)
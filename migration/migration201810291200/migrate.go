@@ -0,0 +1,29 @@
+package migration201810291200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201810291200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlAddDeploymentBackends).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding deployment backends to DB")
+	},
+}
+
+// Every existing deployment ran on EC2 F1.
+const (
+	sqlAddDeploymentBackends = `
+ALTER TABLE deployments ADD COLUMN backend text NOT NULL DEFAULT '';
+UPDATE deployments SET backend = 'ec2-f1';
+ALTER TABLE projects ADD COLUMN deployment_backend text NOT NULL DEFAULT '';
+`
+)
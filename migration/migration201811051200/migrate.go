@@ -0,0 +1,29 @@
+package migration201811051200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201811051200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlAddDeploymentPlacement).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding deployment regions and instance types to DB")
+	},
+}
+
+// Every existing EC2 deployment ran on an f1.2xlarge in us-east-1.
+const (
+	sqlAddDeploymentPlacement = `
+ALTER TABLE deployments ADD COLUMN region text NOT NULL DEFAULT '';
+ALTER TABLE deployments ADD COLUMN instance_type text NOT NULL DEFAULT '';
+UPDATE deployments SET region = 'us-east-1', instance_type = 'f1.2xlarge' WHERE backend = 'ec2-f1';
+`
+)
@@ -0,0 +1,28 @@
+package migration201811121200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201811121200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlAddDeploymentSchedule).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding deployment schedules to DB")
+	},
+}
+
+const (
+	sqlAddDeploymentSchedule = `
+ALTER TABLE deployments ADD COLUMN start_at timestamp with time zone;
+ALTER TABLE deployments ADD COLUMN max_duration integer NOT NULL DEFAULT 0;
+CREATE INDEX deployments_start_at_idx ON deployments (start_at) WHERE start_at IS NOT NULL;
+`
+)
@@ -0,0 +1,41 @@
+package migration201811191200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201811191200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlNotifyQueue).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding queue notification trigger to DB")
+	},
+}
+
+// Notify queues when a job is pushed, or a slot may be free, with the job
+// type as payload. Jobs being dispatched don't free slots, so are left out.
+const (
+	sqlNotifyQueue = `
+CREATE OR REPLACE FUNCTION notify_queue_entry() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('queue_entries', NEW.type);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER queue_entries_notify
+AFTER INSERT OR UPDATE OF status ON queue_entries
+FOR EACH ROW
+WHEN (NEW.status <> 'STARTED')
+EXECUTE PROCEDURE notify_queue_entry();
+
+CREATE INDEX IF NOT EXISTS queue_entries_type_status_idx ON queue_entries (type, status);
+`
+)
@@ -0,0 +1,35 @@
+package migration201811261200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201811261200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlCreateBatchJobAttempts).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding batch job attempts to DB")
+	},
+}
+
+const (
+	sqlCreateBatchJobAttempts = `
+CREATE TABLE batch_job_attempts (
+    id text PRIMARY KEY,
+    batch_job_id bigint NOT NULL,
+    attempt integer NOT NULL,
+    batch_id text,
+    reason text,
+    retried boolean NOT NULL DEFAULT false,
+    created_at timestamp with time zone
+);
+CREATE INDEX idx_batch_job_attempts_batch_job_id ON batch_job_attempts (batch_job_id);
+`
+)
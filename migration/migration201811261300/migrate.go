@@ -0,0 +1,28 @@
+package migration201811261300
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201811261300",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlAddBuildTarget).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding build targets to DB")
+	},
+}
+
+const (
+	// builds made before targets could be chosen were all for the
+	// 04261818 shell
+	sqlAddBuildTarget = `
+ALTER TABLE builds ADD COLUMN target text NOT NULL DEFAULT 'aws-f1-04261818';
+`
+)
@@ -0,0 +1,27 @@
+package migration201812031200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201812031200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlAddBuildOptions).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding build options to DB")
+	},
+}
+
+const (
+	sqlAddBuildOptions = `
+ALTER TABLE builds ADD COLUMN tier text NOT NULL DEFAULT 'standard';
+ALTER TABLE builds ADD COLUMN skip_afi boolean NOT NULL DEFAULT false;
+`
+)
@@ -0,0 +1,29 @@
+package migration201812101200
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"gopkg.in/gormigrate.v1"
+)
+
+var Migration = gormigrate.Migration{
+	ID: "201812101200",
+	Migrate: func(tx *gorm.DB) error {
+		err := tx.Exec(sqlAddQueueHeartbeats).Error
+		return err
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return errors.New("Migration failed. Hit rollback conditions while adding queue heartbeats to DB")
+	},
+}
+
+// Jobs running before heartbeats were sent count as last heard from when
+// they were dispatched.
+const (
+	sqlAddQueueHeartbeats = `
+ALTER TABLE queue_entries ADD COLUMN heartbeat_at timestamp with time zone;
+UPDATE queue_entries SET heartbeat_at = dispatched_at;
+`
+)
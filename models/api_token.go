@@ -0,0 +1,205 @@
+package models
+
+//go:generate mockgen -source=api_token.go -package=models -destination=api_token_mock.go
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dchest/uniuri"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// APITokenPrefix prefixes the username of API token credentials.
+	APITokenPrefix = "tok_"
+
+	// AccessRead is read access to a resource.
+	AccessRead = "read"
+	// AccessWrite is write access to a resource, it implies read access.
+	AccessWrite = "write"
+)
+
+var (
+	// ErrAPITokenInvalid is returned when API token credentials are wrong,
+	// expired or revoked.
+	ErrAPITokenInvalid = errors.New("API token is invalid")
+
+	// scopeResources are the resources an API token can be granted access to.
+	scopeResources = []string{"builds", "simulations", "graphs", "deployments", "projects", "organisations", "user"}
+)
+
+// APITokenRepo handles API tokens.
+type APITokenRepo interface {
+	// Create creates a new token for a user, returning it along with its
+	// secret. The secret is not stored and cannot be retrieved later.
+	Create(userID string, post PostAPIToken) (APIToken, string, error)
+	// ListForUser returns all the tokens belonging to a user.
+	ListForUser(userID string) ([]APIToken, error)
+	// Revoke revokes one of a user's tokens.
+	Revoke(userID string, tokenID string) (APIToken, error)
+	// Authenticate returns the token, with its user, matching the
+	// credentials if it is neither expired nor revoked.
+	Authenticate(tokenID string, secret string) (APIToken, error)
+}
+
+type apiTokenRepo struct{ db *gorm.DB }
+
+// APITokenDataSource returns the data source for API tokens.
+func APITokenDataSource(db *gorm.DB) APITokenRepo {
+	return &apiTokenRepo{db: db}
+}
+
+// Scopes is a list of API token scopes, stored as comma separated text.
+type Scopes []string
+
+// Value satisfies driver.Valuer.
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+// Scan satisfies sql.Scanner.
+func (s *Scopes) Scan(src interface{}) error {
+	var str string
+	switch v := src.(type) {
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into Scopes", src)
+	}
+	*s = Scopes{}
+	if str != "" {
+		*s = strings.Split(str, ",")
+	}
+	return nil
+}
+
+// APIToken model.
+type APIToken struct {
+	uuidHook
+	ID         string     `gorm:"primary_key" json:"id"`
+	User       User       `json:"-" gorm:"ForeignKey:UserID"`
+	UserID     string     `json:"-" gorm:"not_null"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"-"`
+	Scopes     Scopes     `json:"scopes" sql:"type:text"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// PostAPIToken is post request body for a new API token.
+type PostAPIToken struct {
+	Name      string     `json:"name" validate:"nonzero"`
+	Scopes    []string   `json:"scopes" validate:"nonzero"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// LoginToken returns the credentials to use the token with, given its secret.
+func (t APIToken) LoginToken(secret string) string {
+	return fmt.Sprintf("%s%s_%s", APITokenPrefix, t.ID, secret)
+}
+
+// Valid returns if the token can be used at time now.
+func (t APIToken) Valid(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+// Allows returns if the token grants access to resource. Write access
+// implies read access.
+func (t APIToken) Allows(resource string, access string) bool {
+	for _, scope := range t.Scopes {
+		if scope == resource+":"+AccessWrite {
+			return true
+		}
+		if scope == resource+":"+access {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidScope returns if scope is of the form resource:access for a known
+// resource.
+func ValidScope(scope string) bool {
+	parts := strings.Split(scope, ":")
+	if len(parts) != 2 {
+		return false
+	}
+	if parts[1] != AccessRead && parts[1] != AccessWrite {
+		return false
+	}
+	return inSlice(scopeResources, parts[0])
+}
+
+func hashAPITokenSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (repo *apiTokenRepo) Create(userID string, post PostAPIToken) (APIToken, string, error) {
+	secret := uniuri.NewLen(64)
+	token := APIToken{
+		UserID:    userID,
+		Name:      post.Name,
+		Hash:      hashAPITokenSecret(secret),
+		Scopes:    Scopes(post.Scopes),
+		ExpiresAt: post.ExpiresAt,
+	}
+	err := repo.db.Create(&token).Error
+	return token, secret, err
+}
+
+func (repo *apiTokenRepo) ListForUser(userID string) ([]APIToken, error) {
+	tokens := []APIToken{}
+	err := repo.db.Where("user_id = ?", userID).Order("created_at").Find(&tokens).Error
+	return tokens, err
+}
+
+func (repo *apiTokenRepo) Revoke(userID string, tokenID string) (APIToken, error) {
+	var token APIToken
+	err := repo.db.Where("user_id = ?", userID).First(&token, "id = ?", tokenID).Error
+	if err != nil {
+		return token, err
+	}
+	if token.RevokedAt != nil {
+		return token, nil
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	err = repo.db.Model(&token).Update("revoked_at", now).Error
+	return token, err
+}
+
+func (repo *apiTokenRepo) Authenticate(tokenID string, secret string) (APIToken, error) {
+	var token APIToken
+	err := repo.db.Preload("User").First(&token, "id = ?", tokenID).Error
+	if err == gorm.ErrRecordNotFound {
+		return token, ErrAPITokenInvalid
+	}
+	if err != nil {
+		return token, err
+	}
+
+	hash := hashAPITokenSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(token.Hash)) != 1 || !token.Valid(time.Now()) {
+		return token, ErrAPITokenInvalid
+	}
+
+	now := time.Now()
+	err = repo.db.Model(&token).UpdateColumn("last_used_at", now).Error
+	return token, err
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPITokenAllows(t *testing.T) {
+	token := APIToken{Scopes: Scopes{"builds:read", "deployments:write"}}
+
+	cases := []struct {
+		resource string
+		access   string
+		allowed  bool
+	}{
+		{"builds", AccessRead, true},
+		{"builds", AccessWrite, false},
+		{"deployments", AccessRead, true},
+		{"deployments", AccessWrite, true},
+		{"projects", AccessRead, false},
+	}
+	for _, c := range cases {
+		if allowed := token.Allows(c.resource, c.access); allowed != c.allowed {
+			t.Errorf("Allows(%s, %s): expected %v, got %v", c.resource, c.access, c.allowed, allowed)
+		}
+	}
+}
+
+func TestAPITokenValid(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	if !(APIToken{}).Valid(now) {
+		t.Error("Expected token without expiry to be valid")
+	}
+	if !(APIToken{ExpiresAt: &future}).Valid(now) {
+		t.Error("Expected unexpired token to be valid")
+	}
+	if (APIToken{ExpiresAt: &past}).Valid(now) {
+		t.Error("Expected expired token to be invalid")
+	}
+	if (APIToken{RevokedAt: &past}).Valid(now) {
+		t.Error("Expected revoked token to be invalid")
+	}
+}
+
+func TestValidScope(t *testing.T) {
+	for _, scope := range []string{"builds:read", "user:write"} {
+		if !ValidScope(scope) {
+			t.Errorf("Expected %s to be valid", scope)
+		}
+	}
+	for _, scope := range []string{"builds", "builds:admin", "foo:read", "builds:read:write"} {
+		if ValidScope(scope) {
+			t.Errorf("Expected %s to be invalid", scope)
+		}
+	}
+}
+
+func TestScopesScan(t *testing.T) {
+	var scopes Scopes
+	if err := scopes.Scan([]byte("builds:read,user:write")); err != nil {
+		t.Fatal(err)
+	}
+	if len(scopes) != 2 || scopes[0] != "builds:read" || scopes[1] != "user:write" {
+		t.Errorf("Unexpected scopes %v", scopes)
+	}
+
+	if err := scopes.Scan(""); err != nil {
+		t.Fatal(err)
+	}
+	if len(scopes) != 0 {
+		t.Errorf("Expected no scopes, got %v", scopes)
+	}
+}
@@ -12,10 +12,24 @@ import (
 type BatchRepo interface {
 	AddEvent(batchJob BatchJob, event BatchJobEvent) error
 	New(batchID string) BatchJob
+	// SetBatchID records the ID of a batch job submitted after it was
+	// queued.
+	SetBatchID(batchJob BatchJob, batchID string) error
 	GetLogName(batchID string) (logName string, err error)
 	SetLogName(id string, logName string) error
 	ActiveJobsWithoutLogs(time.Time) ([]BatchJob, error)
 	HasStarted(batchID string) (started bool, err error)
+	// ActiveJobs returns the submitted batch jobs which haven't finished,
+	// and have had events since sinceTime, with their events and attempts.
+	ActiveJobs(sinceTime time.Time) ([]BatchJob, error)
+	// Owner returns the kind ("build", "simulation" or "graph") and ID of
+	// what batchJob runs.
+	Owner(batchJob BatchJob) (kind string, id string, err error)
+	// AddAttempt records a failed attempt at running batchJob, and adds
+	// event. If batchID isn't empty, the job was resubmitted as batchID.
+	// Attempts already recorded, e.g. by another replica, are ignored,
+	// and false returned, so a duplicate resubmission can be halted.
+	AddAttempt(batchJob BatchJob, attempt BatchJobAttempt, batchID string, event BatchJobEvent) (bool, error)
 }
 
 const (
@@ -31,6 +45,42 @@ on j.id = started.batch_job_id
         limit 1
     )
 where (log_name = '' and started.timestamp > ?)
+`
+
+	sqlActiveBatchJobs = `
+select j.id as id
+from batch_jobs j
+join batch_job_events latest
+on j.id = latest.batch_job_id
+    and latest.id = (
+        select e1.id
+        from batch_job_events e1
+        where j.id = e1.batch_job_id
+        order by e1.timestamp desc
+        limit 1
+    )
+where j.batch_id <> '' and latest.status in (?) and latest.timestamp > ?
+`
+
+	// locks the batch job, and returns if the attempt with batch ID has
+	// already been recorded, or replaced
+	sqlBatchJobAttempted = `
+select j.batch_id <> ? or exists (
+    select 1
+    from batch_job_attempts a
+    where a.batch_job_id = j.id and a.batch_id = ?
+)
+from batch_jobs j
+where j.id = ?
+for update
+`
+
+	sqlBatchJobOwner = `
+select 'build' as kind, id from builds where batch_job_id = ?
+union all
+select 'simulation' as kind, id from simulations where batch_job_id = ?
+union all
+select 'graph' as kind, id from graphs where batch_job_id = ?
 `
 )
 
@@ -48,6 +98,10 @@ func (repo *batchRepo) New(batchID string) BatchJob {
 	return batchJob
 }
 
+func (repo *batchRepo) SetBatchID(batchJob BatchJob, batchID string) error {
+	return repo.db.Model(&batchJob).Update("batch_id", batchID).Error
+}
+
 // AwaitStarted polls the BatchRepo's DB for the state of the batch job
 // associated with a given ID. It blocks until the batch job has started, unless
 // an error occurs.
@@ -78,11 +132,22 @@ func (repo *batchRepo) HasStarted(batchID string) (bool, error) {
 	return hasStarted(batchJob.Status()), nil
 }
 
-// AddEvent adds an event to the batch service.
+// AddEvent adds an event to the batch service, and queues its webhook
+// deliveries.
 func (repo *batchRepo) AddEvent(batchJob BatchJob, event BatchJobEvent) error {
-	db := repo.db
-	err := db.Model(&batchJob).Association("Events").Append(event).Error
-	return err
+	tx := repo.db.Begin()
+	err := tx.Model(&batchJob).Association("Events").Append(event).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = enqueueBatchJobEvent(tx, batchJob.ID, event)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
 }
 
 // GetLogName takes a BatchJob ID and returns that BatchJob's logname if present
@@ -128,3 +193,87 @@ func (repo *batchRepo) ActiveJobsWithoutLogs(sinceTime time.Time) ([]BatchJob, e
 
 	return batchJobs, nil
 }
+
+func (repo *batchRepo) ActiveJobs(sinceTime time.Time) ([]BatchJob, error) {
+	db := repo.db
+	active := []string{StatusQueued, StatusStarted}
+	rows, err := db.Raw(sqlActiveBatchJobs, active, sinceTime).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	var batchJobs []BatchJob
+	if len(ids) == 0 {
+		return batchJobs, nil
+	}
+	err = db.Preload("Events", func(db *gorm.DB) *gorm.DB {
+		return db.Order("timestamp ASC")
+	}).Preload("Attempts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("attempt ASC")
+	}).Where("id in (?)", ids).Find(&batchJobs).Error
+	return batchJobs, err
+}
+
+func (repo *batchRepo) Owner(batchJob BatchJob) (string, string, error) {
+	var kind, id string
+	row := repo.db.Raw(sqlBatchJobOwner, batchJob.ID, batchJob.ID, batchJob.ID).Row()
+	err := row.Scan(&kind, &id)
+	return kind, id, err
+}
+
+func (repo *batchRepo) AddAttempt(batchJob BatchJob, attempt BatchJobAttempt, batchID string, event BatchJobEvent) (bool, error) {
+	tx := repo.db.Begin()
+	var attempted bool
+	err := tx.Raw(sqlBatchJobAttempted, attempt.BatchID, attempt.BatchID, batchJob.ID).Row().Scan(&attempted)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if attempted {
+		return false, tx.Rollback().Error
+	}
+
+	attempt.BatchJobID = batchJob.ID
+	err = tx.Create(&attempt).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if batchID != "" {
+		// the new job logs to a new stream, which is found once it starts
+		err = tx.Model(&batchJob).Updates(map[string]interface{}{
+			"batch_id": batchID,
+			"log_name": "",
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	err = tx.Model(&batchJob).Association("Events").Append(event).Error
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	err = enqueueBatchJobEvent(tx, batchJob.ID, event)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit().Error
+}
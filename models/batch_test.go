@@ -207,3 +207,58 @@ func TestBatchActiveJobsWithoutLogsWithLogs(t *testing.T) {
 
 	})
 }
+
+func TestBatchActiveJobs(t *testing.T) {
+	RunTransaction(func(db *gorm.DB) {
+		d := BatchDataSource(db)
+
+		started := Build{
+			BatchJob: BatchJob{
+				BatchID: "active-started",
+				Events: []BatchJobEvent{
+					BatchJobEvent{Timestamp: time.Unix(10, 0), Status: StatusQueued},
+					BatchJobEvent{Timestamp: time.Unix(20, 0), Status: StatusStarted},
+				},
+			},
+		}
+		finished := Build{
+			BatchJob: BatchJob{
+				BatchID: "active-finished",
+				Events: []BatchJobEvent{
+					BatchJobEvent{Timestamp: time.Unix(10, 0), Status: StatusStarted},
+					BatchJobEvent{Timestamp: time.Unix(20, 0), Status: StatusCompleted},
+				},
+			},
+		}
+		// queued jobs which haven't been submitted aren't active
+		unsubmitted := Build{BatchJob: d.New("")}
+		for _, build := range []*Build{&started, &finished, &unsubmitted} {
+			err := db.Create(build).Error
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+
+		batchJobs, err := d.ActiveJobs(time.Unix(0, 0))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if len(batchJobs) != 1 || batchJobs[0].ID != started.BatchJob.ID {
+			t.Fatalf("Expected only job %d to be active, got %+v", started.BatchJob.ID, batchJobs)
+		}
+		if len(batchJobs[0].Events) != 2 {
+			t.Errorf("Expected active job's events to be loaded, got %+v", batchJobs[0].Events)
+		}
+
+		kind, id, err := d.Owner(batchJobs[0])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if kind != "build" || id != started.ID {
+			t.Errorf("Expected owner build %s, got %s %s", started.ID, kind, id)
+		}
+	})
+}
@@ -5,6 +5,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
@@ -15,6 +16,9 @@ type BuildRepo interface {
 	GetBuildsWithStatus([]string, int) ([]Build, error)
 	StoreBuildReport(Build, Report) error
 	GetBuildReport(build Build) (BuildReport, error)
+	// ProjectUtilisation returns the utilisation of each of a project's
+	// reported builds, oldest first.
+	ProjectUtilisation(projectID string) ([]UtilisationPoint, error)
 	AddBatchJobToBuild(build *Build, batchJob BatchJob) error
 	ByID(buildID string) (Build, error)
 	ByIDForUser(buildID, userID string) (Build, error)
@@ -40,6 +44,18 @@ ON j.batch_job_id = e.batch_job_id
     )
 WHERE (e.status in (?))
 LIMIT ?
+`
+
+	sqlProjectReports = `SELECT r.build_id, r.report, (
+    SELECT min(timestamp)
+    FROM batch_job_events e
+    WHERE e.batch_job_id = j.batch_job_id
+) AS timestamp
+FROM build_reports r
+JOIN builds j
+ON j.id = r.build_id
+WHERE j.project_id = ?
+ORDER BY timestamp ASC
 `
 )
 
@@ -78,9 +94,31 @@ type Build struct {
 	FPGAImage   string       `json:"-"`
 	Token       string       `json:"-"`
 	Message     string       `json:"message"`
+	Target      string       `json:"target" gorm:"default:'aws-f1-04261818'"`
+	Tier        string       `json:"tier" gorm:"default:'standard'"`
+	SkipAFI     bool         `json:"skip_afi"`
 	Deployments []Deployment `json:"deployments,omitempty" gorm:"ForeignKey:BuildID"`
 }
 
+// BuildTier returns the tier build runs with, or the default tier if it
+// isn't supported.
+func (build Build) BuildTier() BuildTier {
+	if t, ok := LookupBuildTier(build.Tier); ok {
+		return t
+	}
+	t, _ := LookupBuildTier(DefaultBuildTierID)
+	return t
+}
+
+// FPGATarget returns the target build is for, or the default target if it
+// isn't supported.
+func (build Build) FPGATarget() Target {
+	if t, ok := LookupTarget(build.Target); ok {
+		return t
+	}
+	return DefaultTarget()
+}
+
 // The place to upload build input to
 // should be a tar.gz
 func (build Build) InputUrl() string {
@@ -159,10 +197,56 @@ func (repo *buildRepo) GetBuildReport(build Build) (BuildReport, error) {
 	return report, err
 }
 
+// Parse returns the stored report.
+func (r BuildReport) Parse() (Report, error) {
+	report := Report{}
+	err := json.Unmarshal([]byte(r.Report), &report)
+	return report, err
+}
+
+type reportRow struct {
+	BuildID   string
+	Report    string
+	Timestamp *time.Time
+}
+
+// ProjectUtilisation returns the utilisation of each of a project's
+// reported builds, ordered by when they were started.
+func (repo *buildRepo) ProjectUtilisation(projectID string) ([]UtilisationPoint, error) {
+	points := []UtilisationPoint{}
+	var rows []reportRow
+	err := repo.db.Raw(sqlProjectReports, projectID).Scan(&rows).Error
+	if err != nil {
+		return points, err
+	}
+
+	for _, row := range rows {
+		report, err := BuildReport{Report: row.Report}.Parse()
+		if err != nil {
+			return points, err
+		}
+		points = append(points, NewUtilisationPoint(row.BuildID, row.Timestamp, report))
+	}
+	return points, nil
+}
+
 // PostBuild is post request body for a new build.
 type PostBuild struct {
 	ProjectID string `json:"project_id" validate:"nonzero"`
 	Message   string `json:"message"`
+	// Target is the ID of one of Targets, DefaultTargetID if empty.
+	Target string `json:"target"`
+	// Tier is the ID of one of BuildTiers, DefaultBuildTierID if empty.
+	Tier string `json:"tier"`
+	// SkipAFI only synthesises the build, without generating an image
+	// which can be deployed.
+	SkipAFI bool `json:"skip_afi"`
+}
+
+// PostRebuild is the request body to rebuild an existing build's input,
+// it keeps the original's message unless Message is given.
+type PostRebuild struct {
+	Message *string `json:"message"`
 }
 
 func (repo *buildRepo) preload() {
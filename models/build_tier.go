@@ -0,0 +1,58 @@
+package models
+
+// BuildTier is the memory and vCPUs a build's batch job is given.
+type BuildTier struct {
+	ID       string `json:"id"`
+	MemoryMB int64  `json:"memory_mb"`
+	VCPUs    int64  `json:"vcpus"`
+}
+
+// DefaultBuildTierID is the tier of builds which don't choose one, and of
+// builds made before tiers could be chosen.
+const DefaultBuildTierID = "standard"
+
+// BuildTiers are the tiers builds may choose, from smallest to largest.
+var BuildTiers = []BuildTier{
+	{ID: DefaultBuildTierID, MemoryMB: 32000, VCPUs: 8},
+	{ID: "large", MemoryMB: 60000, VCPUs: 16},
+	{ID: "xlarge", MemoryMB: 120000, VCPUs: 32},
+}
+
+// DefaultPlanMaxBuildTier is the largest tier users on plans missing from
+// PlanMaxBuildTiers may build with.
+const DefaultPlanMaxBuildTier = "large"
+
+// PlanMaxBuildTiers are the largest tiers users may build with, by their
+// subscription plan.
+var PlanMaxBuildTiers = map[string]string{
+	PlanOpenSource: DefaultBuildTierID,
+	PlanSingleUser: "xlarge",
+}
+
+// LookupBuildTier returns the tier with id.
+func LookupBuildTier(id string) (BuildTier, bool) {
+	for _, t := range BuildTiers {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return BuildTier{}, false
+}
+
+// PlanAllowsBuildTier returns if users on plan may build with the tier with
+// id.
+func PlanAllowsBuildTier(plan string, id string) bool {
+	max, ok := PlanMaxBuildTiers[plan]
+	if !ok {
+		max = DefaultPlanMaxBuildTier
+	}
+	for _, t := range BuildTiers {
+		if t.ID == id {
+			return true
+		}
+		if t.ID == max {
+			return false
+		}
+	}
+	return false
+}
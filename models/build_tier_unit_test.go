@@ -0,0 +1,23 @@
+package models
+
+import "testing"
+
+func TestPlanAllowsBuildTier(t *testing.T) {
+	cases := []struct {
+		plan    string
+		tier    string
+		allowed bool
+	}{
+		{PlanOpenSource, DefaultBuildTierID, true},
+		{PlanOpenSource, "large", false},
+		{PlanSingleUser, "xlarge", true},
+		{"unknown-plan", "large", true},
+		{"unknown-plan", "xlarge", false},
+		{PlanSingleUser, "unsupported", false},
+	}
+	for _, c := range cases {
+		if allowed := PlanAllowsBuildTier(c.plan, c.tier); allowed != c.allowed {
+			t.Errorf("Expected %s on %s allowed to be %v, Got: %v", c.tier, c.plan, c.allowed, allowed)
+		}
+	}
+}
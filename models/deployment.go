@@ -32,16 +32,48 @@ type DeploymentRepo interface {
 	// ActiveDeployments returns basic information about running deployments.
 	ActiveDeployments(userID string) ([]DeploymentHours, error)
 
+	// FinishedDeployments returns basic information about deployments of
+	// every user which started since, and have terminated.
+	FinishedDeployments(since time.Time) ([]DeploymentHours, error)
+
 	AddEvent(Deployment, DeploymentEvent) error
 	SetIP(Deployment, string) error
+	// SetInstance records the instance running a deployment, along with
+	// the deployment's backend which owns it.
+	SetInstance(Deployment, string) error
 
 	GetWithoutIP() ([]Deployment, error)
+
+	// GetScheduled returns deployments scheduled to start by now which
+	// haven't been started.
+	GetScheduled(now time.Time) ([]Deployment, error)
+	// GetExpired returns running deployments which have been running for
+	// longer than their maximum duration at now.
+	GetExpired(now time.Time) ([]Deployment, error)
 }
 
 type DeploymentHours struct {
-	Id         string
-	Started    time.Time
-	Terminated time.Time
+	Id           string
+	Started      time.Time
+	Terminated   time.Time
+	InstanceType string
+}
+
+// InstanceWeights are how many hours an hour of a deployment counts for,
+// by instance type. Types missing here, including the empty type of older
+// deployments, count for one.
+var InstanceWeights = map[string]int{
+	"f1.2xlarge":  1,
+	"f1.4xlarge":  2,
+	"f1.16xlarge": 8,
+}
+
+// InstanceWeight returns how many hours an hour on instanceType counts for.
+func InstanceWeight(instanceType string) int {
+	if w, ok := InstanceWeights[instanceType]; ok {
+		return w
+	}
+	return 1
 }
 
 type deploymentRepo struct{ db *gorm.DB }
@@ -78,7 +110,7 @@ WHERE (user_id = ? and e.status in (?))
 `
 
 	sqlDeploymentHours = `
-select j.id as id, started.timestamp as started, coalesce(terminated.timestamp, now()) as terminated
+select j.id as id, started.timestamp as started, coalesce(terminated.timestamp, now()) as terminated, j.instance_type as instance_type
 from deployments j
 left join deployment_events started
 on j.id = started.deployment_id
@@ -108,7 +140,7 @@ where (
 `
 
 	sqlDeploymentInstances = `
-select j.id as id, started.timestamp as started, terminated.timestamp as terminated
+select j.id as id, started.timestamp as started, terminated.timestamp as terminated, j.instance_type as instance_type
 from deployments j
 left join deployment_events started
 on j.id = started.deployment_id
@@ -127,6 +159,28 @@ on j.id = terminated.deployment_id
         limit 1
     )
 where user_id = ? and terminated IS NULL
+`
+
+	sqlDeploymentsFinished = `
+select j.id as id, started.timestamp as started, terminated.timestamp as terminated, j.instance_type as instance_type
+from deployments j
+join deployment_events started
+on j.id = started.deployment_id
+    and started.id = (
+        select e1.id
+        from deployment_events e1
+        where j.id = e1.deployment_id and e1.status = 'STARTED'
+        limit 1
+    )
+join deployment_events terminated
+on j.id = terminated.deployment_id
+    and terminated.id = (
+        select e2.id
+        from deployment_events e2
+        where j.id = e2.deployment_id and e2.status = 'TERMINATED'
+        limit 1
+    )
+where started.timestamp > ?
 `
 
 	sqlDeploymentsWithoutIPs = `
@@ -150,13 +204,51 @@ on j.id = terminated.deployment_id
     )
 
 where COALESCE(ip_address, '') = '' and started IS NOT NULL and terminated IS NULL
+`
+
+	sqlDeploymentsScheduled = `
+select j.id
+from deployments j
+where j.start_at <= ? and j.instance_id = ''
+and not exists (
+    select 1
+    from deployment_events e
+    where j.id = e.deployment_id and e.status <> 'SUBMITTED'
+)
+`
+
+	sqlDeploymentsExpired = `
+select distinct j.id
+from deployments j
+join deployment_events started
+on j.id = started.deployment_id and started.status = 'STARTED'
+where j.max_duration > 0
+and started.timestamp + j.max_duration * interval '1 second' < ?
+and not exists (
+    select 1
+    from deployment_events e
+    where j.id = e.deployment_id and e.status in (?)
+)
 `
 )
 
+// AddEvent adds an event to a deployment, and queues its webhook
+// deliveries.
 func (repo *deploymentRepo) AddEvent(dep Deployment, event DeploymentEvent) error {
+	tx := repo.db.Begin()
 	event.DeploymentID = dep.ID
-	err := repo.db.Create(&event).Error
-	return err
+	err := tx.Create(&event).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = enqueueDeploymentEvent(tx, dep.ID, event)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
 }
 
 func (repo *deploymentRepo) SetIP(dep Deployment, ip string) error {
@@ -164,6 +256,14 @@ func (repo *deploymentRepo) SetIP(dep Deployment, ip string) error {
 	return err
 }
 
+func (repo *deploymentRepo) SetInstance(dep Deployment, instanceID string) error {
+	err := repo.db.Model(&dep).Updates(map[string]interface{}{
+		"backend":     dep.Backend,
+		"instance_id": instanceID,
+	}).Error
+	return err
+}
+
 func (repo *deploymentRepo) GetWithUser(userID string) ([]Deployment, error) {
 	deployments := []Deployment{}
 	err := repo.db.Preload("Build").Preload("Build.Project").Preload("Build.Project.User").
@@ -188,9 +288,8 @@ func (repo *deploymentRepo) Query(userID string) *gorm.DB {
 	preloaded := repo.Preload()
 	joined := preloaded.
 		Joins("left join builds on builds.id = deployments.build_id").
-		Joins("left join projects on projects.id = builds.project_id").
-		Where("deployments.user_id=?", userID)
-	return joined
+		Joins("left join projects on projects.id = builds.project_id")
+	return AccessibleBy(joined, "deployments.user_id", userID, RolesRead)
 }
 
 func (repo *deploymentRepo) GetWithStatus(statuses []string, limit int) ([]Deployment, error) {
@@ -272,6 +371,41 @@ func (repo *deploymentRepo) GetWithoutIP() ([]Deployment, error) {
 	return deps, nil
 }
 
+func (repo *deploymentRepo) GetScheduled(now time.Time) ([]Deployment, error) {
+	return repo.getByQuery(sqlDeploymentsScheduled, now)
+}
+
+func (repo *deploymentRepo) GetExpired(now time.Time) ([]Deployment, error) {
+	return repo.getByQuery(sqlDeploymentsExpired, now, []string{StatusTerminating, StatusTerminated, StatusCompleted, StatusErrored})
+}
+
+// getByQuery returns the deployments whose IDs are selected by sql.
+func (repo *deploymentRepo) getByQuery(sql string, values ...interface{}) ([]Deployment, error) {
+	db := repo.db
+	rows, err := db.Raw(sql, values...).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	var deps []Deployment
+	err = db.Preload("Build").Preload("Events", func(db *gorm.DB) *gorm.DB {
+		return db.Order("timestamp ASC")
+	}).Where("id in (?)", ids).Find(&deps).Error
+	return deps, err
+}
+
 func AggregateHoursBetween(deps []DeploymentHours, startTime, endTime time.Time) int {
 	t := 0
 	emptyTime := time.Time{}
@@ -295,8 +429,8 @@ func AggregateHoursBetween(deps []DeploymentHours, startTime, endTime time.Time)
 		if e.After(endTime) {
 			e = endTime
 		}
-		// Round up and convert to an int
-		t += int(math.Ceil(e.Sub(s).Hours()))
+		// Round up and convert to an int, weighted by instance size
+		t += int(math.Ceil(e.Sub(s).Hours())) * InstanceWeight(dep.InstanceType)
 	}
 
 	return t
@@ -354,6 +488,59 @@ func (repo *deploymentRepo) ActiveDeployments(userID string) (deps []DeploymentH
 	return
 }
 
+func (repo *deploymentRepo) FinishedDeployments(since time.Time) (deps []DeploymentHours, err error) {
+	db := repo.db
+
+	rows, err := db.Raw(sqlDeploymentsFinished, since).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deps = []DeploymentHours{}
+	for rows.Next() {
+		var dep DeploymentHours
+		err = db.ScanRows(rows, &dep)
+		if err != nil {
+			return
+		}
+		deps = append(deps, dep)
+	}
+	return
+}
+
+// DefaultDeploymentDuration is how long deployments are expected to run
+// for when there are none to learn from.
+const DefaultDeploymentDuration = time.Hour
+
+// AverageDuration returns how long the finished deployments in deps ran
+// for on average, or DefaultDeploymentDuration if none have finished.
+func AverageDuration(deps []DeploymentHours) time.Duration {
+	var total time.Duration
+	n := 0
+	for _, dep := range deps {
+		if dep.Started.IsZero() || dep.Terminated.Before(dep.Started) {
+			continue
+		}
+		total += dep.Terminated.Sub(dep.Started)
+		n++
+	}
+	if n == 0 {
+		return DefaultDeploymentDuration
+	}
+	return total / time.Duration(n)
+}
+
+// TypicalDeploymentDuration returns how long deployments started since
+// ran for on average.
+func TypicalDeploymentDuration(repo DeploymentRepo, since time.Time) (time.Duration, error) {
+	deps, err := repo.FinishedDeployments(since)
+	if err != nil {
+		return 0, err
+	}
+	return AverageDuration(deps), nil
+}
+
 // monthStart changes t to the beginning of the month in UTC.
 func monthStart(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
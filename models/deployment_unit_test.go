@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregateHoursBetweenWeighted(t *testing.T) {
+	now := time.Now()
+	start := now.Add(-2 * time.Hour)
+
+	depHours := []DeploymentHours{
+		{Id: "legacy", Started: start, Terminated: now},
+		{Id: "small", Started: start, Terminated: now, InstanceType: "f1.2xlarge"},
+		{Id: "large", Started: start, Terminated: now, InstanceType: "f1.16xlarge"},
+	}
+
+	hours := AggregateHoursBetween(depHours, now.AddDate(0, 0, -1), now)
+	if hours != 2+2+16 {
+		t.Errorf("Expected: 20, Got: %d", hours)
+	}
+}
+
+func TestAverageDuration(t *testing.T) {
+	now := time.Now()
+	depHours := []DeploymentHours{
+		{Id: "short", Started: now.Add(-1 * time.Hour), Terminated: now},
+		{Id: "long", Started: now.Add(-3 * time.Hour), Terminated: now},
+		{Id: "never-started", Terminated: now},
+	}
+	if d := AverageDuration(depHours); d != 2*time.Hour {
+		t.Errorf("Expected: 2h, Got: %s", d)
+	}
+	if d := AverageDuration(nil); d != DefaultDeploymentDuration {
+		t.Errorf("Expected: %s, Got: %s", DefaultDeploymentDuration, d)
+	}
+}
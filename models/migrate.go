@@ -12,10 +12,16 @@ func MigrateAll(db *gorm.DB) {
 	db.AutoMigrate(&Build{})
 	db.AutoMigrate(&BatchJob{})
 	db.AutoMigrate(&BatchJobEvent{})
+	db.AutoMigrate(&BatchJobAttempt{})
 	db.AutoMigrate(&Deployment{})
 	db.AutoMigrate(&DeploymentEvent{})
 	db.AutoMigrate(&BuildReport{})
 	db.AutoMigrate(&Graph{})
 	db.AutoMigrate(&QueueEntry{})
 	db.AutoMigrate(&SimulationReport{})
+	db.AutoMigrate(&APIToken{})
+	db.AutoMigrate(&Organisation{})
+	db.AutoMigrate(&Membership{})
+	db.AutoMigrate(&Webhook{})
+	db.AutoMigrate(&WebhookDelivery{})
 }
@@ -110,12 +110,14 @@ func CreateOrUpdateUser(db *gorm.DB, u User, createNew bool) (User, error) {
 // Project model.
 type Project struct {
 	uuidHook
-	ID          string  `gorm:"primary_key" json:"id"`
-	User        User    `json:"-" gorm:"ForeignKey:UserID"` // Project belongs to User
-	UserID      string  `json:"-"`
-	Name        string  `json:"name"`
-	Builds      []Build `json:"builds,omitempty" gorm:"ForeignKey:ProjectID"`
-	Simulations []Build `json:"simulations,omitempty" gorm:"ForeignKey:ProjectID"`
+	ID                string  `gorm:"primary_key" json:"id"`
+	User              User    `json:"-" gorm:"ForeignKey:UserID"` // Project belongs to User
+	UserID            string  `json:"-"`
+	OrganisationID    *string `json:"organisation_id,omitempty"` // Project may be shared with an Organisation
+	Name              string  `json:"name"`
+	DeploymentBackend string  `json:"deployment_backend,omitempty"` // Backend for deployments which don't pick one
+	Builds            []Build `json:"builds,omitempty" gorm:"ForeignKey:ProjectID"`
+	Simulations       []Build `json:"simulations,omitempty" gorm:"ForeignKey:ProjectID"`
 }
 
 // PostBatchEvent is post request body for batch events.
@@ -141,17 +143,28 @@ type Deployment struct {
 	BuildID      string            `json:"-"`
 	Command      string            `json:"command"`
 	Token        string            `json:"-"`
+	Backend      string            `json:"backend"` // Backend owns InstanceID
+	Region       string            `json:"region,omitempty"`
+	InstanceType string            `json:"instance_type,omitempty"`
+	StartAt      *time.Time        `json:"start_at,omitempty"`     // Deployment is scheduled to start at
+	MaxDuration  int               `json:"max_duration,omitempty"` // Seconds deployment may run for, if not 0
 	InstanceID   string            `json:"-"`
 	IPAddress    string            `json:"ip_address"`
 	UserID       string            `gorm:"not_null"`
 	SpotInstance bool              `json:"-" sql:"NOT NULL;DEFAULT:false"`
 	Events       []DeploymentEvent `json:"events" gorm:"ForeignKey:DeploymentID"`
+	Queue        *QueuePosition    `json:"queue,omitempty" gorm:"-"` // Set while the deployment is queued
 }
 
 // PostDeployment is post request body for new deployment.
 type PostDeployment struct {
-	BuildID string `json:"build_id" validate:"nonzero"`
-	Command string `json:"command" validate:"nonzero"`
+	BuildID      string     `json:"build_id" validate:"nonzero"`
+	Command      string     `json:"command" validate:"nonzero"`
+	Backend      string     `json:"backend"`
+	Region       string     `json:"region"`
+	InstanceType string     `json:"instance_type"`
+	StartAt      *time.Time `json:"start_at"`
+	MaxDuration  int        `json:"max_duration"`
 }
 
 // Status returns deployment status.
@@ -189,6 +202,8 @@ type BatchJob struct {
 	BatchID string          `json:"-"`
 	LogName string          `json:"-"`
 	Events  []BatchJobEvent `json:"events" gorm:"ForeignKey:BatchJobId"`
+	// Attempts are the earlier runs of the job which failed, oldest first.
+	Attempts []BatchJobAttempt `json:"attempts,omitempty" gorm:"ForeignKey:BatchJobId"`
 }
 
 // Status returns the status of the job.
@@ -227,6 +242,19 @@ type BatchJobEvent struct {
 	Code       int       `json:"code"`
 }
 
+// BatchJobAttempt is a run of a batch job which failed, and whether it was
+// retried.
+type BatchJobAttempt struct {
+	uuidHook
+	ID         string    `gorm:"primary_key" json:"-"`
+	BatchJobID int64     `json:"-"`
+	Attempt    int       `json:"attempt"`
+	BatchID    string    `json:"-"`
+	Reason     string    `json:"reason"`
+	Retried    bool      `json:"retried"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 // HasStarted returns if the job has started.
 func (d *Deployment) HasStarted() bool {
 	return hasStarted(d.Status())
@@ -0,0 +1,160 @@
+package models
+
+//go:generate mockgen -source=organisation.go -package=models -destination=organisation_mock.go
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// RoleOwner can manage an organisation and its members.
+	RoleOwner = "owner"
+	// RoleMember can use an organisation's projects.
+	RoleMember = "member"
+	// RoleViewer can see an organisation's projects, but not change them.
+	RoleViewer = "viewer"
+
+	sqlOrganisationsWithRole = `SELECT organisation_id FROM memberships WHERE user_id = ? AND role IN (?)`
+)
+
+var (
+	// RolesRead are the roles allowed to read an organisation's projects.
+	RolesRead = []string{RoleOwner, RoleMember, RoleViewer}
+	// RolesWrite are the roles allowed to modify an organisation's projects.
+	RolesWrite = []string{RoleOwner, RoleMember}
+)
+
+// OrganisationRepo handles organisations and their memberships.
+type OrganisationRepo interface {
+	// Create creates an organisation, with owner as its first owner.
+	Create(name string, owner User) (Organisation, error)
+	// ListForUser returns the organisations a user is a member of.
+	ListForUser(userID string) ([]Organisation, error)
+	// ByIDForUser returns an organisation, with its members, if the user
+	// is one of them.
+	ByIDForUser(orgID string, userID string) (Organisation, error)
+	// Role returns the role of a user in an organisation.
+	Role(orgID string, userID string) (string, error)
+	// SetMember adds a user to an organisation, or changes their role.
+	SetMember(orgID string, userID string, role string) (Membership, error)
+	// RemoveMember removes a user from an organisation.
+	RemoveMember(orgID string, userID string) error
+}
+
+type organisationRepo struct{ db *gorm.DB }
+
+// OrganisationDataSource returns the data source for organisations.
+func OrganisationDataSource(db *gorm.DB) OrganisationRepo {
+	return &organisationRepo{db: db}
+}
+
+// Organisation model.
+type Organisation struct {
+	uuidHook
+	ID          string       `gorm:"primary_key" json:"id"`
+	Name        string       `json:"name"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Memberships []Membership `json:"members,omitempty" gorm:"ForeignKey:OrganisationID"`
+}
+
+// Membership model.
+type Membership struct {
+	uuidHook
+	ID             string    `gorm:"primary_key" json:"-"`
+	OrganisationID string    `json:"-" gorm:"not_null"`
+	User           User      `json:"user" gorm:"ForeignKey:UserID"`
+	UserID         string    `json:"-" gorm:"not_null"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// PostOrganisation is post request body for a new organisation.
+type PostOrganisation struct {
+	Name string `json:"name" validate:"nonzero"`
+}
+
+// PostMembership is post request body for adding a member to an organisation.
+type PostMembership struct {
+	Email string `json:"email" validate:"nonzero"`
+	Role  string `json:"role" validate:"nonzero"`
+}
+
+// ValidRole returns if role is a known organisation role.
+func ValidRole(role string) bool {
+	return inSlice(RolesRead, role)
+}
+
+// CanWrite returns if role may modify an organisation's projects.
+func CanWrite(role string) bool {
+	return inSlice(RolesWrite, role)
+}
+
+// AccessibleBy restricts a query joined on projects to rows which userID
+// owns through ownerColumn, or which belong to an organisation userID is a
+// member of with one of roles.
+func AccessibleBy(db *gorm.DB, ownerColumn string, userID string, roles []string) *gorm.DB {
+	return db.Where(ownerColumn+" = ? OR projects.organisation_id IN ("+sqlOrganisationsWithRole+")", userID, userID, roles)
+}
+
+func (repo *organisationRepo) Create(name string, owner User) (Organisation, error) {
+	org := Organisation{Name: name}
+	tx := repo.db.Begin()
+	err := tx.Create(&org).Error
+	if err != nil {
+		tx.Rollback()
+		return org, err
+	}
+
+	membership := Membership{OrganisationID: org.ID, UserID: owner.ID, Role: RoleOwner}
+	err = tx.Create(&membership).Error
+	if err != nil {
+		tx.Rollback()
+		return org, err
+	}
+
+	membership.User = owner
+	org.Memberships = []Membership{membership}
+	return org, tx.Commit().Error
+}
+
+func (repo *organisationRepo) ListForUser(userID string) ([]Organisation, error) {
+	orgs := []Organisation{}
+	err := repo.db.Where("id IN ("+sqlOrganisationsWithRole+")", userID, RolesRead).
+		Order("name").
+		Find(&orgs).Error
+	return orgs, err
+}
+
+func (repo *organisationRepo) ByIDForUser(orgID string, userID string) (Organisation, error) {
+	var org Organisation
+	err := repo.db.Preload("Memberships").
+		Preload("Memberships.User").
+		Where("id IN ("+sqlOrganisationsWithRole+")", userID, RolesRead).
+		First(&org, "id = ?", orgID).Error
+	return org, err
+}
+
+func (repo *organisationRepo) Role(orgID string, userID string) (string, error) {
+	var membership Membership
+	err := repo.db.Where("organisation_id = ? AND user_id = ?", orgID, userID).First(&membership).Error
+	return membership.Role, err
+}
+
+func (repo *organisationRepo) SetMember(orgID string, userID string, role string) (Membership, error) {
+	membership := Membership{OrganisationID: orgID, UserID: userID}
+	err := repo.db.Where(membership).
+		Assign(Membership{Role: role}).
+		FirstOrCreate(&membership).Error
+	if err != nil {
+		return membership, err
+	}
+	err = repo.db.First(&membership.User, "id = ?", userID).Error
+	return membership, err
+}
+
+func (repo *organisationRepo) RemoveMember(orgID string, userID string) error {
+	return repo.db.Where("organisation_id = ? AND user_id = ?", orgID, userID).
+		Delete(&Membership{}).Error
+}
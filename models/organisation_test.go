@@ -0,0 +1,63 @@
+// +build integration
+
+package models
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+func TestOrganisationSharedProjects(t *testing.T) {
+	RunTransaction(func(db *gorm.DB) {
+		repo := organisationRepo{db}
+
+		owner := User{Email: "owner@example.com"}
+		viewer := User{Email: "viewer@example.com"}
+		stranger := User{Email: "stranger@example.com"}
+		db.Create(&owner)
+		db.Create(&viewer)
+		db.Create(&stranger)
+
+		org, err := repo.Create("team", owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = repo.SetMember(org.ID, viewer.ID, RoleViewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		project := Project{UserID: owner.ID, Name: "shared", OrganisationID: &org.ID}
+		db.Create(&project)
+
+		var tests = []struct {
+			user     User
+			roles    []string
+			expected int
+		}{
+			{owner, RolesWrite, 1},
+			{viewer, RolesRead, 1},
+			{viewer, RolesWrite, 0},
+			{stranger, RolesRead, 0},
+		}
+		for _, test := range tests {
+			var count int
+			AccessibleBy(db.Model(&Project{}), "projects.user_id", test.user.ID, test.roles).
+				Where("projects.id = ?", project.ID).
+				Count(&count)
+			if count != test.expected {
+				t.Errorf("%s with roles %v: expected %d projects, got %d", test.user.Email, test.roles, test.expected, count)
+			}
+		}
+
+		err = repo.RemoveMember(org.ID, viewer.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = repo.ByIDForUser(org.ID, viewer.ID)
+		if err != gorm.ErrRecordNotFound {
+			t.Fatalf("Expected removed member not to find organisation, got %v", err)
+		}
+	})
+}
@@ -2,16 +2,73 @@ package models
 
 import "time"
 
+// DefaultPlanWeight is the queue weight of jobs of users on plans missing
+// from PlanWeights.
+const DefaultPlanWeight = 2
+
+// PlanWeights are the queue weights of jobs by their user's subscription
+// plan. Jobs with higher weights are dispatched first.
+var PlanWeights = map[string]int{
+	PlanOpenSource: 1,
+	PlanSingleUser: 2,
+}
+
+// PlanWeight returns the queue weight of jobs of users on plan.
+func PlanWeight(plan string) int {
+	if w, ok := PlanWeights[plan]; ok {
+		return w
+	}
+	return DefaultPlanWeight
+}
+
+// DefaultPlanQueueLimit is how many jobs of a type users on plans missing
+// from PlanQueueLimits may have waiting in a queue at once.
+const DefaultPlanQueueLimit = 5
+
+// PlanQueueLimits are how many jobs of a type users may have waiting in a
+// queue at once, by their subscription plan.
+var PlanQueueLimits = map[string]int{
+	PlanOpenSource: 2,
+	PlanSingleUser: 5,
+}
+
+// PlanQueueLimit returns how many jobs of a type users on plan may have
+// waiting in a queue at once.
+func PlanQueueLimit(plan string) int {
+	if l, ok := PlanQueueLimits[plan]; ok {
+		return l
+	}
+	return DefaultPlanQueueLimit
+}
+
 // QueueEntry is a queue entry.
 type QueueEntry struct {
 	uuidHook
-	ID           string `gorm:"primary_key"`
-	Type         string `gorm:"default:'deployment'"`
-	TypeID       string `gorm:"not_null"`
-	User         User   `json:"-" gorm:"ForeignKey:UserID"`
-	UserID       string `json:"-"`
-	Weight       int
-	Status       string
-	CreatedAt    time.Time
-	DispatchedAt time.Time
+	ID           string    `gorm:"primary_key" json:"id"`
+	Type         string    `gorm:"default:'deployment'" json:"type"`
+	TypeID       string    `gorm:"not_null" json:"type_id"`
+	User         User      `json:"-" gorm:"ForeignKey:UserID"`
+	UserID       string    `json:"user_id"`
+	Weight       int       `json:"weight"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	DispatchedAt time.Time `json:"dispatched_at"`
+	// HeartbeatAt is when the replica running the job last reported it
+	// still running.
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+}
+
+// PutQueueEntry is the request body to reprioritise a queue entry.
+type PutQueueEntry struct {
+	Weight *int `json:"weight" validate:"nonzero"`
+}
+
+// QueuePosition is where a job waiting in a queue is, and when it's
+// expected to start.
+type QueuePosition struct {
+	TypeID         string    `json:"id"`
+	UserID         string    `json:"-"`
+	Position       int       `json:"position"`   // 1 is next to be dispatched
+	Dispatched     int       `json:"dispatched"` // jobs running ahead of it
+	EstimatedStart time.Time `json:"estimated_start"`
 }
@@ -1,5 +1,9 @@
 package models
 
+import (
+	"time"
+)
+
 type Report struct {
 	ModuleName      string       `json:"moduleName"`
 	PartName        string       `json:"partName"`
@@ -27,3 +31,106 @@ type PartDetail struct {
 	Available   int     `json:"available"`
 	Utilisation float32 `json:"utilisation"`
 }
+
+// Total returns the group's totals, without its breakdown.
+func (g GroupSummary) Total() PartDetail {
+	return PartDetail{
+		Description: g.Description,
+		Used:        g.Used,
+		Available:   g.Available,
+		Utilisation: g.Utilisation,
+	}
+}
+
+// UtilisationPoint is the resource utilisation of one build, for charting
+// utilisation over time.
+type UtilisationPoint struct {
+	BuildID   string     `json:"build_id"`
+	Timestamp *time.Time `json:"timestamp"`
+	PartName  string     `json:"partName"`
+	Lut       PartDetail `json:"lut"`
+	Register  PartDetail `json:"register"`
+	BlockRam  PartDetail `json:"blockRam"`
+	UltraRam  PartDetail `json:"ultraRam"`
+	DspBlock  PartDetail `json:"dspBlock"`
+}
+
+// NewUtilisationPoint summarises the report of a build.
+func NewUtilisationPoint(buildID string, timestamp *time.Time, report Report) UtilisationPoint {
+	return UtilisationPoint{
+		BuildID:   buildID,
+		Timestamp: timestamp,
+		PartName:  report.PartName,
+		Lut:       report.LutSummary.Total(),
+		Register:  report.RegSummary.Total(),
+		BlockRam:  report.BlockRamSummary.Total(),
+		UltraRam:  report.UltraRamSummary,
+		DspBlock:  report.DspBlockSummary,
+	}
+}
+
+// PartDelta is the change in usage of a resource between two builds.
+type PartDelta struct {
+	Description string  `json:"description"`
+	Before      int     `json:"before"`
+	After       int     `json:"after"`
+	Used        int     `json:"used"`
+	Utilisation float32 `json:"utilisation"`
+}
+
+func diffPart(before, after PartDetail) PartDelta {
+	description := after.Description
+	if description == "" {
+		description = before.Description
+	}
+	return PartDelta{
+		Description: description,
+		Before:      before.Used,
+		After:       after.Used,
+		Used:        after.Used - before.Used,
+		Utilisation: after.Utilisation - before.Utilisation,
+	}
+}
+
+func diffDetails(before, after PartDetails) map[string]PartDelta {
+	deltas := make(map[string]PartDelta)
+	for name, b := range before {
+		deltas[name] = diffPart(b, after[name])
+	}
+	for name, a := range after {
+		if _, ok := before[name]; !ok {
+			deltas[name] = diffPart(PartDetail{}, a)
+		}
+	}
+	return deltas
+}
+
+// ReportDiff is the change in resource utilisation from one build's report
+// to another's, overall and per part.
+type ReportDiff struct {
+	BuildID   string                          `json:"build_id"`
+	AgainstID string                          `json:"against_id"`
+	Summary   map[string]PartDelta            `json:"summary"`
+	Detail    map[string]map[string]PartDelta `json:"detail"`
+}
+
+// DiffReports returns the changes from the report of build againstID to the
+// report of build buildID.
+func DiffReports(againstID string, before Report, buildID string, after Report) ReportDiff {
+	return ReportDiff{
+		BuildID:   buildID,
+		AgainstID: againstID,
+		Summary: map[string]PartDelta{
+			"lut":      diffPart(before.LutSummary.Total(), after.LutSummary.Total()),
+			"register": diffPart(before.RegSummary.Total(), after.RegSummary.Total()),
+			"blockRam": diffPart(before.BlockRamSummary.Total(), after.BlockRamSummary.Total()),
+			"ultraRam": diffPart(before.UltraRamSummary, after.UltraRamSummary),
+			"dspBlock": diffPart(before.DspBlockSummary, after.DspBlockSummary),
+		},
+		Detail: map[string]map[string]PartDelta{
+			"lut":      diffDetails(before.LutSummary.Detail, after.LutSummary.Detail),
+			"register": diffDetails(before.RegSummary.Detail, after.RegSummary.Detail),
+			"blockRam": diffDetails(before.BlockRamSummary.Detail, after.BlockRamSummary.Detail),
+		},
+	}
+}
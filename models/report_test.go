@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestDiffReports(t *testing.T) {
+	before := Report{
+		LutSummary: GroupSummary{
+			Description: "CLB LUTs",
+			Used:        70,
+			Utilisation: 0.01,
+			Detail: PartDetails{
+				"lutLogic":  {Description: "LUT as Logic", Used: 3},
+				"lutMemory": {Description: "LUT as Memory", Used: 67},
+			},
+		},
+		DspBlockSummary: PartDetail{Description: "DSPs", Used: 4},
+	}
+	after := Report{
+		LutSummary: GroupSummary{
+			Description: "CLB LUTs",
+			Used:        100,
+			Utilisation: 0.03,
+			Detail: PartDetails{
+				"lutLogic": {Description: "LUT as Logic", Used: 10},
+				"lutShift": {Description: "LUT as Shift Register", Used: 90},
+			},
+		},
+		DspBlockSummary: PartDetail{Description: "DSPs", Used: 2},
+	}
+
+	diff := DiffReports("a", before, "b", after)
+
+	if diff.BuildID != "b" || diff.AgainstID != "a" {
+		t.Errorf("Unexpected build IDs %s and %s", diff.BuildID, diff.AgainstID)
+	}
+
+	lut := diff.Summary["lut"]
+	if lut.Before != 70 || lut.After != 100 || lut.Used != 30 {
+		t.Errorf("Unexpected LUT delta %+v", lut)
+	}
+	if dsp := diff.Summary["dspBlock"]; dsp.Used != -2 {
+		t.Errorf("Expected 2 fewer DSPs, got %+v", dsp)
+	}
+
+	var tests = []struct {
+		part string
+		used int
+	}{
+		{"lutLogic", 7},
+		{"lutMemory", -67},
+		{"lutShift", 90},
+	}
+	for _, test := range tests {
+		delta, ok := diff.Detail["lut"][test.part]
+		if !ok {
+			t.Errorf("Missing delta for %s", test.part)
+			continue
+		}
+		if delta.Used != test.used {
+			t.Errorf("%s: expected delta %d, got %d", test.part, test.used, delta.Used)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+//go:generate mockgen -source=retention.go -package=models -destination=retention_mock.go
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	sqlActivelyDeployedBuilds = `SELECT DISTINCT j.build_id
+FROM deployments j
+LEFT join deployment_events e
+ON j.id = e.deployment_id
+	AND e.timestamp = (
+		SELECT max(timestamp)
+		FROM deployment_events e1
+		WHERE j.id = e1.deployment_id
+	)
+WHERE e.status IS NULL OR e.status NOT IN (?)
+`
+)
+
+// RetentionRepo answers what stored objects belong to, so that old ones
+// can be removed.
+type RetentionRepo interface {
+	// ProjectIDs maps the IDs of rows in table, one of builds, simulations
+	// or graphs, to their project's ID. IDs with no row are left out.
+	ProjectIDs(table string, ids []string) (map[string]string, error)
+	// ActivelyDeployedBuilds returns the IDs of builds with a deployment
+	// which hasn't finished.
+	ActivelyDeployedBuilds() ([]string, error)
+}
+
+type retentionRepo struct{ db *gorm.DB }
+
+// RetentionDataSource returns the data source for retention.
+func RetentionDataSource(db *gorm.DB) RetentionRepo {
+	return &retentionRepo{db: db}
+}
+
+type projectRow struct {
+	ID        string
+	ProjectID string
+}
+
+// maxIDsPerQuery keeps ProjectIDs' queries well below postgres' limit of
+// 65535 bound parameters.
+const maxIDsPerQuery = 1000
+
+func (repo *retentionRepo) ProjectIDs(table string, ids []string) (map[string]string, error) {
+	projects := make(map[string]string)
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > maxIDsPerQuery {
+			batch = batch[:maxIDsPerQuery]
+		}
+		ids = ids[len(batch):]
+
+		var rows []projectRow
+		err := repo.db.Table(table).
+			Select("id, project_id").
+			Where("id IN (?)", batch).
+			Scan(&rows).Error
+		if err != nil {
+			return projects, err
+		}
+		for _, row := range rows {
+			projects[row.ID] = row.ProjectID
+		}
+	}
+	return projects, nil
+}
+
+func (repo *retentionRepo) ActivelyDeployedBuilds() ([]string, error) {
+	rows, err := repo.db.Raw(sqlActivelyDeployedBuilds, []string{StatusTerminated, StatusCompleted, StatusErrored}).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
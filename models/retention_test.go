@@ -0,0 +1,36 @@
+// +build integration
+
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+func TestProjectIDsManyIDs(t *testing.T) {
+	RunTransaction(func(db *gorm.DB) {
+		project := Project{UserID: "retention-user"}
+		db.Create(&project)
+		first := Build{ProjectID: project.ID}
+		db.Create(&first)
+		last := Build{ProjectID: project.ID}
+		db.Create(&last)
+
+		// more IDs than fit in one query, with the builds at either end
+		ids := []string{first.ID}
+		for i := 0; i < 2*maxIDsPerQuery; i++ {
+			ids = append(ids, fmt.Sprintf("00000000-0000-0000-0000-%012d", i))
+		}
+		ids = append(ids, last.ID)
+
+		projects, err := RetentionDataSource(db).ProjectIDs("builds", ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(projects) != 2 || projects[first.ID] != project.ID || projects[last.ID] != project.ID {
+			t.Errorf("Expected both builds in project %s, got %v", project.ID, projects)
+		}
+	})
+}
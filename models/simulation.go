@@ -4,6 +4,7 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/jinzhu/gorm"
 )
@@ -36,6 +37,11 @@ type Simulation struct {
 	Command    string   `json:"command"`
 }
 
+// InputUrl is the place to upload simulation input to, a tar.gz.
+func (s Simulation) InputUrl() string {
+	return fmt.Sprintf("simulation/%s/simulation.tar.gz", s.ID)
+}
+
 // Status returns simulation status.
 func (s *Simulation) Status() string {
 	events := s.BatchJob.Events
@@ -52,6 +58,12 @@ type PostSimulation struct {
 	Command   string `json:"command" validate:"nonzero"`
 }
 
+// PostRerun is the request body to rerun an existing simulation's input,
+// it keeps the original's command unless Command is given.
+type PostRerun struct {
+	Command *string `json:"command"`
+}
+
 type SimulationReport struct {
 	uuidHook
 	ID           string     `gorm:"primary_key" json:"-"`
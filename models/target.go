@@ -0,0 +1,48 @@
+package models
+
+// Target is an FPGA platform, or shell, which builds can target.
+type Target struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Part        string `json:"part"`
+	PartFamily  string `json:"part_family"`
+	Device      string `json:"device"`
+}
+
+// DefaultTargetID is the target of builds which don't choose one, and of
+// builds made before targets could be chosen.
+const DefaultTargetID = "aws-f1-04261818"
+
+// Targets are the supported build targets, in the order they're listed.
+var Targets = []Target{
+	{
+		ID:          DefaultTargetID,
+		Description: "AWS F1, shell 04261818 (SDx 2018.2)",
+		Part:        "xcvu9p-flgb2104-2-i",
+		PartFamily:  "virtexuplus",
+		Device:      "xilinx_aws-vu9p-f1-04261818_dynamic_5_0",
+	},
+	{
+		ID:          "aws-f1-dynamic-5.0",
+		Description: "AWS F1, shell dynamic 5.0 (SDx 2017.4)",
+		Part:        "xcvu9p-flgb2104-2-i",
+		PartFamily:  "virtexuplus",
+		Device:      "xilinx_aws-vu9p-f1_dynamic_5_0",
+	},
+}
+
+// LookupTarget returns the supported target with id.
+func LookupTarget(id string) (Target, bool) {
+	for _, t := range Targets {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Target{}, false
+}
+
+// DefaultTarget returns the target of builds which don't choose one.
+func DefaultTarget() Target {
+	t, _ := LookupTarget(DefaultTargetID)
+	return t
+}
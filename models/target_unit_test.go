@@ -0,0 +1,17 @@
+package models
+
+import "testing"
+
+func TestBuildFPGATarget(t *testing.T) {
+	other := Targets[len(Targets)-1]
+	if target := (Build{Target: other.ID}).FPGATarget(); target != other {
+		t.Errorf("Expected: %v, Got: %v", other, target)
+	}
+	// builds made before targets could be chosen, or for targets no longer
+	// supported, are for the default target
+	for _, id := range []string{"", "unsupported"} {
+		if target := (Build{Target: id}).FPGATarget(); target.ID != DefaultTargetID {
+			t.Errorf("Expected target of %q to be %s, Got: %s", id, DefaultTargetID, target.ID)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package models
+
+//go:generate mockgen -source=webhook.go -package=models -destination=webhook_mock.go
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/dchest/uniuri"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// DeliveryPending is a delivery waiting to be (re)tried.
+	DeliveryPending = "pending"
+	// DeliveryDelivered is a delivery the receiver accepted.
+	DeliveryDelivered = "delivered"
+	// DeliveryFailed is a delivery which ran out of attempts.
+	DeliveryFailed = "failed"
+)
+
+const (
+	sqlBatchJobProject = `
+select 'build' as kind, id, project_id from builds where batch_job_id = ?
+union all
+select 'simulation' as kind, id, project_id from simulations where batch_job_id = ?
+union all
+select 'graph' as kind, id, project_id from graphs where batch_job_id = ?
+`
+
+	// deployments of another user's (public) build aren't sent to the
+	// build project's webhooks
+	sqlDeploymentProject = `
+select p.id
+from deployments d
+join builds b on b.id = d.build_id
+join projects p on p.id = b.project_id
+where d.id = ? and p.user_id = d.user_id
+`
+)
+
+// WebhookRepo handles webhook subscriptions and their deliveries.
+type WebhookRepo interface {
+	// Create subscribes a webhook to a project's events.
+	Create(projectID string, post PostWebhook) (Webhook, error)
+	// ListForProject returns the webhooks subscribed to a project.
+	ListForProject(projectID string) ([]Webhook, error)
+	// Delete unsubscribes a project's webhook.
+	Delete(projectID string, webhookID string) error
+	// Enqueue creates a pending delivery of event for every webhook of
+	// the project subscribed to it.
+	Enqueue(projectID string, event WebhookEvent) ([]WebhookDelivery, error)
+	// Due returns pending deliveries, with their webhook, whose next
+	// attempt is before now.
+	Due(now time.Time, limit int) ([]WebhookDelivery, error)
+	// RecordAttempt saves the outcome of an attempt at a delivery.
+	RecordAttempt(delivery WebhookDelivery) error
+	// ListDeliveries returns the most recent deliveries of a webhook.
+	ListDeliveries(webhookID string, limit int) ([]WebhookDelivery, error)
+}
+
+type webhookRepo struct{ db *gorm.DB }
+
+// WebhookDataSource returns the data source for webhooks.
+func WebhookDataSource(db *gorm.DB) WebhookRepo {
+	return &webhookRepo{db: db}
+}
+
+// EventTypes is a list of webhook event types, stored as comma separated text.
+type EventTypes []string
+
+// Value satisfies driver.Valuer.
+func (e EventTypes) Value() (driver.Value, error) {
+	return Scopes(e).Value()
+}
+
+// Scan satisfies sql.Scanner.
+func (e *EventTypes) Scan(src interface{}) error {
+	var s Scopes
+	err := s.Scan(src)
+	*e = EventTypes(s)
+	return err
+}
+
+// Webhook model.
+type Webhook struct {
+	uuidHook
+	ID         string     `gorm:"primary_key" json:"id"`
+	Project    Project    `json:"-" gorm:"ForeignKey:ProjectID"`
+	ProjectID  string     `json:"project_id" gorm:"not_null"`
+	URL        string     `json:"url"`
+	Secret     string     `json:"-"`
+	EventTypes EventTypes `json:"event_types" sql:"type:text"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewWebhook is a webhook along with its signing secret, which is only
+// shown when it is created.
+type NewWebhook struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// PostWebhook is post request body for a new webhook. An empty list of
+// event types subscribes to every event.
+type PostWebhook struct {
+	URL        string   `json:"url" validate:"nonzero,regexp=^https?://"`
+	EventTypes []string `json:"event_types"`
+}
+
+// Subscribed returns if the webhook wants events of type eventType. Event
+// types are of the form kind.status, and kind.* subscribes to every event
+// of a kind.
+func (w Webhook) Subscribed(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	kind := strings.SplitN(eventType, ".", 2)[0]
+	for _, t := range w.EventTypes {
+		if t == eventType || t == kind+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is the body POSTed to webhooks when a job changes state.
+type WebhookEvent struct {
+	Type       string    `json:"type"`
+	ProjectID  string    `json:"project_id"`
+	ResourceID string    `json:"resource_id"`
+	Status     string    `json:"status"`
+	Message    string    `json:"message,omitempty"`
+	Code       int       `json:"code"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// NewWebhookEvent returns the event for a job of kind entering status.
+func NewWebhookEvent(kind string, projectID string, resourceID string, status string, message string, code int, timestamp time.Time) WebhookEvent {
+	return WebhookEvent{
+		Type:       kind + "." + strings.ToLower(status),
+		ProjectID:  projectID,
+		ResourceID: resourceID,
+		Status:     status,
+		Message:    message,
+		Code:       code,
+		Timestamp:  timestamp,
+	}
+}
+
+// WebhookDelivery model, an event to be POSTed to a webhook.
+type WebhookDelivery struct {
+	uuidHook
+	ID            string     `gorm:"primary_key" json:"id"`
+	Webhook       Webhook    `json:"-" gorm:"ForeignKey:WebhookID"`
+	WebhookID     string     `json:"webhook_id" gorm:"not_null"`
+	EventType     string     `json:"event_type"`
+	Payload       string     `json:"payload" sql:"type:text"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	ResponseCode  int        `json:"response_code"`
+	Error         string     `json:"error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (repo *webhookRepo) Create(projectID string, post PostWebhook) (Webhook, error) {
+	hook := Webhook{
+		ProjectID:  projectID,
+		URL:        post.URL,
+		Secret:     uniuri.NewLen(32),
+		EventTypes: EventTypes(post.EventTypes),
+	}
+	err := repo.db.Create(&hook).Error
+	return hook, err
+}
+
+func (repo *webhookRepo) ListForProject(projectID string) ([]Webhook, error) {
+	hooks := []Webhook{}
+	err := repo.db.Where("project_id = ?", projectID).Order("created_at").Find(&hooks).Error
+	return hooks, err
+}
+
+func (repo *webhookRepo) Delete(projectID string, webhookID string) error {
+	var hook Webhook
+	err := repo.db.Where("project_id = ?", projectID).First(&hook, "id = ?", webhookID).Error
+	if err != nil {
+		return err
+	}
+	return repo.db.Delete(&hook).Error
+}
+
+func (repo *webhookRepo) Enqueue(projectID string, event WebhookEvent) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	hooks, err := repo.ListForProject(projectID)
+	if err != nil || len(hooks) == 0 {
+		return deliveries, err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return deliveries, err
+	}
+
+	for _, hook := range hooks {
+		if !hook.Subscribed(event.Type) {
+			continue
+		}
+		delivery := WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        DeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		err = repo.db.Create(&delivery).Error
+		if err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (repo *webhookRepo) Due(now time.Time, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	err := repo.db.Preload("Webhook").
+		Where("status = ? AND next_attempt_at <= ?", DeliveryPending, now).
+		Where("webhook_id IN (SELECT id FROM webhooks)").
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (repo *webhookRepo) RecordAttempt(delivery WebhookDelivery) error {
+	return repo.db.Model(&delivery).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_code":   delivery.ResponseCode,
+		"error":           delivery.Error,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"delivered_at":    delivery.DeliveredAt,
+	}).Error
+}
+
+func (repo *webhookRepo) ListDeliveries(webhookID string, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	err := repo.db.Where("webhook_id = ?", webhookID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// enqueueBatchJobEvent queues deliveries of a batch job's event to the
+// webhooks of the project of the build, simulation or graph running it.
+func enqueueBatchJobEvent(db *gorm.DB, batchJobID int64, event BatchJobEvent) error {
+	var kind, id, projectID string
+	row := db.Raw(sqlBatchJobProject, batchJobID, batchJobID, batchJobID).Row()
+	err := row.Scan(&kind, &id, &projectID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	webhookEvent := NewWebhookEvent(kind, projectID, id, event.Status, event.Message, event.Code, eventTime(event.Timestamp))
+	_, err = WebhookDataSource(db).Enqueue(projectID, webhookEvent)
+	return err
+}
+
+// enqueueDeploymentEvent queues deliveries of a deployment's event to the
+// webhooks of its build's project.
+func enqueueDeploymentEvent(db *gorm.DB, deploymentID string, event DeploymentEvent) error {
+	var projectID string
+	err := db.Raw(sqlDeploymentProject, deploymentID).Row().Scan(&projectID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	webhookEvent := NewWebhookEvent("deployment", projectID, deploymentID, event.Status, event.Message, event.Code, eventTime(event.Timestamp))
+	_, err = WebhookDataSource(db).Enqueue(projectID, webhookEvent)
+	return err
+}
+
+func eventTime(timestamp time.Time) time.Time {
+	if timestamp.IsZero() {
+		return time.Now()
+	}
+	return timestamp
+}
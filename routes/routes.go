@@ -14,6 +14,7 @@ import (
 	"github.com/ReconfigureIO/platform/service/deployment"
 	"github.com/ReconfigureIO/platform/service/events"
 	"github.com/ReconfigureIO/platform/service/leads"
+	"github.com/ReconfigureIO/platform/service/logs"
 	"github.com/ReconfigureIO/platform/service/storage"
 	"github.com/gin-gonic/contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -28,6 +29,7 @@ func SetupRoutes(
 	r *gin.Engine,
 	db *gorm.DB,
 	awsService batch.Service,
+	deploymentLogs logs.LogSource,
 	events events.EventService,
 	leads leads.Leads,
 	storage storage.Service,
@@ -65,17 +67,23 @@ func SetupRoutes(
 	apiRoutes := r.Group("/", middleware.TokenAuth(db, events, config), middleware.RequiresUser())
 
 	billing := api.Billing{}
+	apiToken := api.APIToken{
+		Repo: models.APITokenDataSource(db),
+	}
 	profile := profile.Profile{
 		DB:    db,
 		Leads: leads,
 	}
-	billingRoutes := apiRoutes.Group("/user")
+	billingRoutes := apiRoutes.Group("/user", middleware.RequiresScope("user"))
 	{
 		billingRoutes.GET("", profile.Get)
 		billingRoutes.PUT("", profile.Update)
 		billingRoutes.GET("/payment-info", billing.Get)
 		billingRoutes.POST("/payment-info", billing.Replace)
 		billingRoutes.GET("/hours-remaining", billing.RemainingHours)
+		billingRoutes.GET("/tokens", apiToken.List)
+		billingRoutes.POST("/tokens", apiToken.Create)
+		billingRoutes.DELETE("/tokens/:id", apiToken.Delete)
 	}
 
 	build := api.Build{
@@ -87,29 +95,53 @@ func SetupRoutes(
 		Repo:            buildRepo,
 		BatchRepo:       batchRepo,
 	}
-	buildRoute := apiRoutes.Group("/builds")
+	buildRoute := apiRoutes.Group("/builds", middleware.RequiresScope("builds"))
 	{
 		buildRoute.GET("", build.List)
 		buildRoute.POST("", build.Create)
 		buildRoute.GET("/:id", build.Get)
+		buildRoute.DELETE("/:id", build.Delete)
 		buildRoute.PUT("/:id/input", build.Input)
+		buildRoute.POST("/:id/input/url", build.InputURL)
+		buildRoute.POST("/:id/input/complete", build.InputComplete)
+		buildRoute.POST("/:id/rebuild", build.Rebuild)
 		buildRoute.GET("/:id/logs", build.Logs)
+		buildRoute.GET("/:id/status/stream", build.StatusStream)
 		buildRoute.GET("/:id/reports", build.Report)
-		if config.Env == "development-on-prem" {
-			buildRoute.GET("/:id/artifacts", build.DownloadArtifact)
-		}
+		buildRoute.GET("/:id/reports/diff", build.ReportDiff)
+		buildRoute.GET("/:id/artifacts", build.DownloadArtifact)
+		buildRoute.GET("/:id/debug", build.DownloadDebug)
 	}
 
 	project := api.Project{
 		Events:          events,
 		PublicProjectID: publicProjectID,
+		DeployService:   deploy,
 	}
-	projectRoute := apiRoutes.Group("/projects")
+	webhook := api.Webhook{}
+	projectRoute := apiRoutes.Group("/projects", middleware.RequiresScope("projects"))
 	{
 		projectRoute.GET("", project.List)
 		projectRoute.POST("", project.Create)
 		projectRoute.PUT("/:id", project.Update)
 		projectRoute.GET("/:id", project.Get)
+		projectRoute.GET("/:id/reports", project.Reports)
+		projectRoute.GET("/:id/webhooks", webhook.List)
+		projectRoute.POST("/:id/webhooks", webhook.Create)
+		projectRoute.DELETE("/:id/webhooks/:webhook_id", webhook.Delete)
+		projectRoute.GET("/:id/webhooks/:webhook_id/deliveries", webhook.Deliveries)
+	}
+
+	organisation := api.Organisation{
+		Repo: models.OrganisationDataSource(db),
+	}
+	organisationRoute := apiRoutes.Group("/organisations", middleware.RequiresScope("organisations"))
+	{
+		organisationRoute.GET("", organisation.List)
+		organisationRoute.POST("", organisation.Create)
+		organisationRoute.GET("/:id", organisation.Get)
+		organisationRoute.PUT("/:id/members", organisation.SetMember)
+		organisationRoute.DELETE("/:id/members/:user_id", organisation.RemoveMember)
 	}
 
 	simulation := api.Simulation{
@@ -119,13 +151,18 @@ func SetupRoutes(
 		Storage:    storage,
 		Repo:       simRepo,
 	}
-	simulationRoute := apiRoutes.Group("/simulations")
+	simulationRoute := apiRoutes.Group("/simulations", middleware.RequiresScope("simulations"))
 	{
 		simulationRoute.GET("", simulation.List)
 		simulationRoute.POST("", simulation.Create)
 		simulationRoute.GET("/:id", simulation.Get)
+		simulationRoute.DELETE("/:id", simulation.Delete)
 		simulationRoute.PUT("/:id/input", simulation.Input)
+		simulationRoute.POST("/:id/input/url", simulation.InputURL)
+		simulationRoute.POST("/:id/input/complete", simulation.InputComplete)
+		simulationRoute.POST("/:id/rerun", simulation.Rerun)
 		simulationRoute.GET("/:id/logs", simulation.Logs)
+		simulationRoute.GET("/:id/status/stream", simulation.StatusStream)
 		simulationRoute.GET("/:id/reports", simulation.Report)
 	}
 
@@ -135,13 +172,18 @@ func SetupRoutes(
 		Events:     events,
 		Storage:    storage,
 	}
-	graphRoute := apiRoutes.Group("/graphs")
+	graphRoute := apiRoutes.Group("/graphs", middleware.RequiresScope("graphs"))
 	{
 		graphRoute.GET("", graph.List)
 		graphRoute.POST("", graph.Create)
 		graphRoute.GET("/:id", graph.Get)
+		graphRoute.DELETE("/:id", graph.Delete)
 		graphRoute.PUT("/:id/input", graph.Input)
+		graphRoute.POST("/:id/input/url", graph.InputURL)
+		graphRoute.POST("/:id/input/complete", graph.InputComplete)
 		graphRoute.GET("/:id/graph", graph.Download)
+		graphRoute.GET("/:id/logs", graph.Logs)
+		graphRoute.GET("/:id/status/stream", graph.StatusStream)
 	}
 
 	deployment := api.Deployment{
@@ -149,30 +191,40 @@ func SetupRoutes(
 		Events:           events,
 		Storage:          storage,
 		DeployService:    deploy,
-		AWS:              awsService,
+		LogSource:        deploymentLogs,
 		UseSpotInstances: config.FeatureUseSpotInstances,
 		PublicProjectID:  publicProjectID,
 	}
-	deploymentRoute := apiRoutes.Group("/deployments")
+	deploymentRoute := apiRoutes.Group("/deployments", middleware.RequiresScope("deployments"))
 	{
 		deploymentRoute.GET("", deployment.List)
 		deploymentRoute.POST("", deployment.Create)
 		deploymentRoute.GET("/:id", deployment.Get)
+		deploymentRoute.DELETE("/:id", deployment.Delete)
 		deploymentRoute.GET("/:id/logs", deployment.Logs)
+		deploymentRoute.GET("/:id/status/stream", deployment.StatusStream)
 	}
 
+	target := api.Target{}
+	apiRoutes.GET("/targets", target.List)
+
+	queueHandler := api.Queue{}
+	apiRoutes.GET("/queue", middleware.RequiresScope("deployments"), queueHandler.Get)
+
 	eventRoutes := r.Group("", middleware.TokenAuth(db, events, config))
 	{
-		eventRoutes.POST("/builds/:id/events", build.CreateEvent)
-		eventRoutes.POST("/simulations/:id/events", simulation.CreateEvent)
-		eventRoutes.POST("/graphs/:id/events", graph.CreateEvent)
-		eventRoutes.POST("/deployments/:id/events", deployment.CreateEvent)
+		// jobs post with their own ?token=, API tokens need write access
+		// to the job's resource
+		eventRoutes.POST("/builds/:id/events", middleware.RequiresScope("builds"), build.CreateEvent)
+		eventRoutes.POST("/simulations/:id/events", middleware.RequiresScope("simulations"), simulation.CreateEvent)
+		eventRoutes.POST("/graphs/:id/events", middleware.RequiresScope("graphs"), graph.CreateEvent)
+		eventRoutes.POST("/deployments/:id/events", middleware.RequiresScope("deployments"), deployment.CreateEvent)
 	}
 
 	reportRoutes := r.Group("", middleware.TokenAuth(db, events, config))
 	{
-		reportRoutes.POST("/builds/:id/reports", build.CreateReport)
-		reportRoutes.POST("/simulations/:id/reports", simulation.CreateReport)
+		reportRoutes.POST("/builds/:id/reports", middleware.RequiresScope("builds"), build.CreateReport)
+		reportRoutes.POST("/simulations/:id/reports", middleware.RequiresScope("simulations"), simulation.CreateReport)
 	}
 	return r
 }
@@ -0,0 +1,126 @@
+package batch
+
+import (
+	"context"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/aws"
+	"github.com/ReconfigureIO/platform/service/logs"
+	awsaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsbatch "github.com/aws/aws-sdk-go/service/batch"
+	"github.com/aws/aws-sdk-go/service/batch/batchiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// awsBatchBackend runs jobs on AWS Batch, or fake-batch, with their logs
+// in CloudWatch.
+type awsBatchBackend struct {
+	api  batchiface.BatchAPI
+	logs *logs.CloudWatch
+	conf aws.ServiceConfig
+}
+
+// NewAWSBatch returns a backend submitting jobs to conf's queue, with
+// conf's job definition.
+func NewAWSBatch(conf aws.ServiceConfig) Backend {
+	sess := session.Must(session.NewSession(awsaws.NewConfig().WithRegion("us-east-1").WithEndpoint(conf.EndPoint)))
+	return &awsBatchBackend{
+		api: awsbatch.New(sess),
+		logs: &logs.CloudWatch{
+			CloudWatchLogsAPI: cloudwatchlogs.New(sess),
+			LogGroup:          conf.LogGroup,
+		},
+		conf: conf,
+	}
+}
+
+func (b *awsBatchBackend) Submit(ctx context.Context, job Job) (string, error) {
+	overrides := &awsbatch.ContainerOverrides{}
+	if len(job.Command) > 0 {
+		overrides.Command = awsaws.StringSlice(job.Command)
+	}
+	if job.MemoryMB > 0 {
+		overrides.Memory = awsaws.Int64(job.MemoryMB)
+	}
+	if job.VCPUs > 0 {
+		overrides.Vcpus = awsaws.Int64(job.VCPUs)
+	}
+	for name, value := range job.Env {
+		overrides.Environment = append(overrides.Environment, &awsbatch.KeyValuePair{
+			Name:  awsaws.String(name),
+			Value: awsaws.String(value),
+		})
+	}
+
+	resp, err := b.api.SubmitJobWithContext(ctx, &awsbatch.SubmitJobInput{
+		JobDefinition:      awsaws.String(b.conf.JobDefinition), // Required
+		JobName:            awsaws.String(job.Name),             // Required
+		JobQueue:           awsaws.String(b.conf.Queue),         // Required
+		ContainerOverrides: overrides,
+	})
+	if err != nil {
+		return "", err
+	}
+	return *resp.JobId, nil
+}
+
+// awsBatchStatus maps an AWS Batch job status onto a batch job status.
+func awsBatchStatus(status string) string {
+	switch status {
+	case awsbatch.JobStatusStarting, awsbatch.JobStatusRunning:
+		return models.StatusStarted
+	case awsbatch.JobStatusSucceeded:
+		return models.StatusCompleted
+	case awsbatch.JobStatusFailed:
+		return models.StatusErrored
+	default:
+		return models.StatusQueued
+	}
+}
+
+func (b *awsBatchBackend) Describe(ctx context.Context, id string) (JobDetail, error) {
+	resp, err := b.api.DescribeJobsWithContext(ctx, &awsbatch.DescribeJobsInput{
+		Jobs: awsaws.StringSlice([]string{id}),
+	})
+	if err != nil {
+		return JobDetail{}, err
+	}
+	if len(resp.Jobs) == 0 {
+		return JobDetail{}, ErrNotFound
+	}
+
+	job := resp.Jobs[0]
+	detail := JobDetail{
+		ID:     id,
+		Status: awsBatchStatus(awsaws.StringValue(job.Status)),
+		Reason: awsaws.StringValue(job.StatusReason),
+	}
+	detail.Reasons = []string{detail.Reason}
+	if job.Container != nil {
+		detail.ExitCode = int(awsaws.Int64Value(job.Container.ExitCode))
+		detail.LogName = awsaws.StringValue(job.Container.LogStreamName)
+		detail.Reasons = append(detail.Reasons, awsaws.StringValue(job.Container.Reason))
+	}
+	for _, attempt := range job.Attempts {
+		detail.Reasons = append(detail.Reasons, awsaws.StringValue(attempt.StatusReason))
+		if attempt.Container != nil {
+			detail.Reasons = append(detail.Reasons, awsaws.StringValue(attempt.Container.Reason))
+		}
+	}
+	return detail, nil
+}
+
+func (b *awsBatchBackend) Terminate(ctx context.Context, id string, reason string) error {
+	_, err := b.api.TerminateJobWithContext(ctx, &awsbatch.TerminateJobInput{
+		JobId:  awsaws.String(id),     // Required
+		Reason: awsaws.String(reason), // Required
+	})
+	return err
+}
+
+// Logs returns the log group of the job definition's CloudWatch log
+// streams.
+func (b *awsBatchBackend) Logs() logs.LogSource {
+	return b.logs
+}
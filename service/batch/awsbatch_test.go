@@ -0,0 +1,25 @@
+package batch
+
+import (
+	"testing"
+
+	"github.com/ReconfigureIO/platform/models"
+	awsbatch "github.com/aws/aws-sdk-go/service/batch"
+)
+
+func TestAWSBatchStatus(t *testing.T) {
+	expected := map[string]string{
+		awsbatch.JobStatusSubmitted: models.StatusQueued,
+		awsbatch.JobStatusPending:   models.StatusQueued,
+		awsbatch.JobStatusRunnable:  models.StatusQueued,
+		awsbatch.JobStatusStarting:  models.StatusStarted,
+		awsbatch.JobStatusRunning:   models.StatusStarted,
+		awsbatch.JobStatusSucceeded: models.StatusCompleted,
+		awsbatch.JobStatusFailed:    models.StatusErrored,
+	}
+	for status, want := range expected {
+		if got := awsBatchStatus(status); got != want {
+			t.Errorf("Expected %s to be %s, got %s", status, want, got)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/aws"
+	"github.com/ReconfigureIO/platform/service/logs"
+)
+
+// ErrNotFound is returned for jobs a backend doesn't know of.
+var ErrNotFound = errors.New("batch job not found")
+
+// Backend runs batch jobs, e.g. on AWS Batch or a docker daemon.
+type Backend interface {
+	// Submit starts running job, and returns its ID.
+	Submit(ctx context.Context, job Job) (string, error)
+	// Describe returns the state of the job with id.
+	Describe(ctx context.Context, id string) (JobDetail, error)
+	// Terminate stops the job with id, for reason.
+	Terminate(ctx context.Context, id string, reason string) error
+	// Logs returns where the logs of jobs are kept, by their JobDetail's
+	// LogName.
+	Logs() logs.LogSource
+}
+
+// Job is what a backend runs. The image, and the command when none is
+// given, are decided by the backend.
+type Job struct {
+	Name     string
+	Command  []string
+	Env      map[string]string
+	MemoryMB int64
+	VCPUs    int64
+}
+
+// JobDetail is the state of a job, as its backend sees it.
+type JobDetail struct {
+	ID string
+	// Status is one of the models.Status* batch job statuses.
+	Status string
+	Reason string
+	// Reasons are every reason the backend gave, Reason first, including
+	// those of attempts it retried itself.
+	Reasons  []string
+	ExitCode int
+	// LogName is the name of the job's logs in its backend's LogSource,
+	// if it has any yet.
+	LogName string
+}
+
+// HasFinished returns if the job is no longer running.
+func (d JobDetail) HasFinished() bool {
+	return d.Status == models.StatusCompleted ||
+		d.Status == models.StatusErrored ||
+		d.Status == models.StatusTerminated
+}
+
+// invalidJobNameChars are the characters AWS Batch doesn't allow in job
+// names.
+var invalidJobNameChars = regexp.MustCompile("[^a-zA-Z0-9_-]")
+
+// maxJobNameLength is the longest job name AWS Batch allows.
+const maxJobNameLength = 128
+
+// jobName names a batch job running kind with id, for project, so it can
+// be found in the backend's console.
+func jobName(kind string, id string, projectID string) string {
+	name := kind + "-" + id
+	if projectID != "" {
+		name += "-project-" + projectID
+	}
+	name = invalidJobNameChars.ReplaceAllString(name, "-")
+	if len(name) > maxJobNameLength {
+		name = name[:maxJobNameLength]
+	}
+	return name
+}
+
+// service is a Service running jobs on a Backend.
+type service struct {
+	backend Backend
+	logs    logs.LogSource
+	conf    aws.ServiceConfig
+}
+
+// New returns a service running jobs on backend. conf's bucket is where
+// their input and output is stored.
+func New(backend Backend, conf aws.ServiceConfig) Service {
+	return &service{backend: backend, logs: backend.Logs(), conf: conf}
+}
+
+func (s *service) s3Url(key string) string {
+	return "s3://" + s.conf.Bucket + "/" + key
+}
+
+// RunBuild runs our build process.
+func (s *service) RunBuild(build models.Build, callbackURL string, reportsURL string) (string, error) {
+	tier := build.BuildTier()
+	target := build.FPGATarget()
+	generateAFI := "yes"
+	if build.SkipAFI {
+		generateAFI = "no"
+	}
+
+	return s.backend.Submit(context.Background(), Job{
+		Name:     jobName("build", build.ID, build.ProjectID),
+		MemoryMB: tier.MemoryMB,
+		VCPUs:    tier.VCPUs,
+		Env: map[string]string{
+			"PART":         target.Part,
+			"PART_FAMILY":  target.PartFamily,
+			"INPUT_URL":    s.s3Url(build.InputUrl()),
+			"CALLBACK_URL": callbackURL,
+			"DEBUG_URL":    s.s3Url(build.DebugUrl()),
+			"DEVICE":       target.Device,
+			"DEVICE_FULL":  target.Device,
+			"OUTPUT_URL":   s.s3Url(build.ArtifactUrl()),
+			"REPORT_URL":   reportsURL,
+			"DCP_KEY":      "/dcp/" + build.ID,
+			"LOG_KEY":      "/dcp-logs/" + build.ID,
+			"GENERATE_AFI": generateAFI,
+		},
+	})
+}
+
+// RunSimulation runs our simulation process.
+func (s *service) RunSimulation(sim models.Simulation, callbackURL string, reportsURL string) (string, error) {
+	target := models.DefaultTarget()
+	return s.backend.Submit(context.Background(), Job{
+		Name:    jobName("simulation", sim.ID, sim.ProjectID),
+		Command: []string{"/opt/simulate.sh"},
+		Env: map[string]string{
+			"PART":         target.Part,
+			"PART_FAMILY":  target.PartFamily,
+			"INPUT_URL":    s.s3Url(sim.InputUrl()),
+			"CALLBACK_URL": callbackURL,
+			"REPORT_URL":   reportsURL,
+			"CMD":          sim.Command,
+			"DEVICE":       target.Device,
+			"DEVICE_FULL":  target.Device,
+		},
+	})
+}
+
+// RunGraph runs our graph process.
+func (s *service) RunGraph(graph models.Graph, callbackURL string) (string, error) {
+	return s.backend.Submit(context.Background(), Job{
+		Name:    jobName("graph", graph.ID, graph.ProjectID),
+		Command: []string{"/opt/graph.sh"},
+		Env: map[string]string{
+			"INPUT_URL":    s.s3Url(graph.InputUrl()),
+			"CALLBACK_URL": callbackURL,
+			"OUTPUT_URL":   s.s3Url(graph.ArtifactUrl()),
+		},
+	})
+}
+
+// HaltJob terminates a running batch job.
+func (s *service) HaltJob(batchID string) error {
+	return s.backend.Terminate(context.Background(), batchID, "User request")
+}
+
+// GetJobDetail describes a batch job.
+func (s *service) GetJobDetail(id string) (JobDetail, error) {
+	return s.backend.Describe(context.Background(), id)
+}
+
+// Backend returns the backend batch jobs run on.
+func (s *service) Backend() Backend {
+	return s.backend
+}
+
+// Logs returns where batch jobs' logs are kept.
+func (s *service) Logs() logs.LogSource {
+	return s.logs
+}
+
+// Conf is used to retrieve the service's config.
+func (s *service) Conf() *aws.ServiceConfig {
+	return &s.conf
+}
@@ -3,24 +3,117 @@ package batch
 //go:generate mockgen -source=batch.go -package=batch -destination=batch_mock.go
 
 import (
+	"fmt"
+
 	"github.com/ReconfigureIO/platform/models"
 	"github.com/ReconfigureIO/platform/service/aws"
-	"github.com/aws/aws-sdk-go/service/batch"
-	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/ReconfigureIO/platform/service/logs"
+)
+
+const (
+	// BackendAWSBatch runs batch jobs on AWS Batch, or fake-batch.
+	BackendAWSBatch = "aws-batch"
+	// BackendDocker runs batch jobs as containers on a docker daemon.
+	BackendDocker = "docker"
+	// BackendKubernetes runs batch jobs as Kubernetes Jobs.
+	BackendKubernetes = "kubernetes"
 )
 
 // Service is a Batch service.
 type Service interface {
 	RunBuild(build models.Build, callbackURL string, reportsURL string) (string, error)
 	RunGraph(graph models.Graph, callbackURL string) (string, error)
-	RunSimulation(inputArtifactURL string, callbackURL string, reportsURL string, command string) (string, error)
-	RunDeployment(command string) (string, error)
+	RunSimulation(sim models.Simulation, callbackURL string, reportsURL string) (string, error)
 
 	HaltJob(batchID string) error
-	GetJobDetail(id string) (*batch.JobDetail, error)
-
-	NewStream(stream cloudwatchlogs.LogStream) *aws.Stream
-	GetJobStream(string) (*cloudwatchlogs.LogStream, error)
+	GetJobDetail(id string) (JobDetail, error)
+	// Backend returns the backend batch jobs run on.
+	Backend() Backend
+	// Logs returns where batch jobs' logs are kept, by their log names.
+	Logs() logs.LogSource
 
 	Conf() *aws.ServiceConfig
 }
+
+// Config chooses the backend batch jobs run on.
+type Config struct {
+	Backend string `env:"RECO_BATCH_BACKEND" envDefault:"aws-batch"`
+
+	// Image is the image jobs run with the docker and kubernetes backends.
+	// On AWS Batch it's set by the job definition.
+	Image string `env:"RECO_BATCH_IMAGE" envDefault:"398048034572.dkr.ecr.us-east-1.amazonaws.com/reconfigureio/build-framework/sdaccel-builder:v0.17.5"`
+	// Binds are the host paths, as host:container, mounted into docker and
+	// kubernetes jobs, e.g. the Xilinx tools.
+	Binds []string `env:"RECO_BATCH_BINDS" envDefault:"/opt/Xilinx:/opt/Xilinx"`
+	// Env are extra KEY=VALUE variables docker and kubernetes jobs are
+	// given, e.g. the Xilinx tools' paths and licence.
+	Env []string `env:"RECO_BATCH_ENV"`
+
+	// DockerHost is the docker daemon jobs run on with the docker
+	// backend, or empty for the one in DOCKER_HOST.
+	DockerHost string `env:"RECO_BATCH_DOCKER_HOST"`
+	// DockerNetwork is the network docker jobs are attached to, which
+	// must be able to reach the API and storage.
+	DockerNetwork string `env:"RECO_BATCH_DOCKER_NETWORK"`
+	// DockerRegistryAuth is the base64 encoded JSON credentials, as in
+	// X-Registry-Auth, Image is pulled with when it isn't on the docker
+	// daemon already, or empty for public images.
+	DockerRegistryAuth string `env:"RECO_BATCH_DOCKER_REGISTRY_AUTH"`
+
+	// KubernetesConfig is the kubeconfig file of the cluster jobs run on
+	// with the kubernetes backend, or empty when running in the cluster.
+	KubernetesConfig    string `env:"RECO_BATCH_K8S_CONFIG"`
+	KubernetesNamespace string `env:"RECO_BATCH_K8S_NAMESPACE" envDefault:"default"`
+	// KubernetesIAMRole is the role kubernetes jobs are given by kube2iam,
+	// to read their input and write their output.
+	KubernetesIAMRole string `env:"RECO_BATCH_K8S_IAM_ROLE"`
+
+	// LogSource is where jobs' logs are read from, when it isn't their
+	// backend: "fake-batch", for jobs run by fake-batch, or "file", for
+	// jobs writing their logs to LogDir.
+	LogSource string `env:"RECO_BATCH_LOG_SOURCE"`
+	LogDir    string `env:"RECO_BATCH_LOG_DIR"`
+}
+
+const (
+	// LogSourceFakeBatch reads logs from fake-batch, at the AWS endpoint.
+	LogSourceFakeBatch = "fake-batch"
+	// LogSourceFile reads logs from files.
+	LogSourceFile = "file"
+)
+
+// NewFromConfig returns a service running jobs on the backend conf
+// chooses. Their input and output is stored in awsConf's bucket.
+func NewFromConfig(conf Config, awsConf aws.ServiceConfig) (Service, error) {
+	var backend Backend
+	switch conf.Backend {
+	case BackendAWSBatch:
+		backend = NewAWSBatch(awsConf)
+	case BackendDocker:
+		var err error
+		backend, err = NewDockerHost(conf, conf.DockerHost)
+		if err != nil {
+			return nil, err
+		}
+	case BackendKubernetes:
+		var err error
+		backend, err = NewKubernetesCluster(conf)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("batch backend %s is not supported", conf.Backend)
+	}
+
+	s := &service{backend: backend, logs: backend.Logs(), conf: awsConf}
+	switch conf.LogSource {
+	case "":
+	case LogSourceFakeBatch:
+		s.logs = &logs.Archive{Endpoint: awsConf.EndPoint}
+	case LogSourceFile:
+		s.logs = &logs.File{Dir: conf.LogDir}
+	default:
+		return nil, fmt.Errorf("batch log source %s is not supported", conf.LogSource)
+	}
+	return s, nil
+}
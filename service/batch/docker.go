@@ -0,0 +1,240 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/logs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// DockerClient is the part of the docker client used for batch jobs.
+type DockerClient interface {
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+	ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error)
+	ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error
+	ContainerStop(ctx context.Context, containerID string, timeout *time.Duration) error
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	ContainerLogs(ctx context.Context, container string, options types.ContainerLogsOptions) (io.ReadCloser, error)
+}
+
+// dockerStopTimeout is how long a job has to exit after being asked to
+// stop, before it's killed.
+const dockerStopTimeout = 30 * time.Second
+
+// dockerBackend runs batch jobs as containers on a docker daemon, as
+// fake-batch does, without pretending to be AWS Batch. Job IDs are
+// container IDs. Finished containers are kept, so their logs can still be
+// read, and are labelled responsible=reco-batch.
+type dockerBackend struct {
+	client DockerClient
+	conf   Config
+}
+
+// NewDocker returns a backend running jobs on the docker daemon client
+// talks to.
+func NewDocker(conf Config, client DockerClient) Backend {
+	return &dockerBackend{client: client, conf: conf}
+}
+
+// NewDockerHost returns a backend running jobs on the docker daemon at
+// host, or the one in DOCKER_HOST when host is empty.
+func NewDockerHost(conf Config, host string) (Backend, error) {
+	var (
+		c   *client.Client
+		err error
+	)
+	if host == "" {
+		c, err = client.NewEnvClient()
+	} else {
+		c, err = client.NewClient(host, "", nil, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure docker client for %q: %v", host, err)
+	}
+	return NewDocker(conf, c), nil
+}
+
+// dockerEnv passes job's environment to its container, after the extra
+// variables configured for all jobs. As with fake-batch, storage
+// credentials are passed through from our own environment.
+func (b *dockerBackend) dockerEnv(job Job) []string {
+	env := []string{
+		"AWS_DEFAULT_REGION=us-east-1",
+		"AWS_ACCESS_KEY_ID=" + os.Getenv("AWS_ACCESS_KEY_ID"),
+		"AWS_SECRET_ACCESS_KEY=" + os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		"S3_ENDPOINT=" + os.Getenv("S3_ENDPOINT"),
+	}
+	env = append(env, b.conf.Env...)
+
+	names := make([]string, 0, len(job.Env))
+	for name := range job.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+job.Env[name])
+	}
+	return env
+}
+
+// pullImage pulls the jobs' image, unless the daemon already has it.
+func (b *dockerBackend) pullImage(ctx context.Context) error {
+	_, _, err := b.client.ImageInspectWithRaw(ctx, b.conf.Image)
+	if err == nil || !client.IsErrNotFound(err) {
+		return err
+	}
+
+	pull, err := b.client.ImagePull(ctx, b.conf.Image, types.ImagePullOptions{
+		RegistryAuth: b.conf.DockerRegistryAuth,
+	})
+	if err != nil {
+		return err
+	}
+	// the pull only finishes once its progress has been read
+	_, err = io.Copy(ioutil.Discard, pull)
+	pull.Close()
+	return err
+}
+
+func (b *dockerBackend) Submit(ctx context.Context, job Job) (string, error) {
+	err := b.pullImage(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	created, err := b.client.ContainerCreate(ctx,
+		&container.Config{
+			Image: b.conf.Image,
+			Cmd:   job.Command,
+			Env:   b.dockerEnv(job),
+			Labels: map[string]string{
+				"responsible": "reco-batch",
+				"job-name":    job.Name,
+			},
+		},
+		&container.HostConfig{
+			Binds:       b.conf.Binds,
+			NetworkMode: container.NetworkMode(b.conf.DockerNetwork),
+			Resources: container.Resources{
+				Memory:   job.MemoryMB * 1024 * 1024,
+				NanoCPUs: job.VCPUs * 1e9,
+			},
+		},
+		nil,
+		"",
+	)
+	if err != nil {
+		return "", err
+	}
+
+	err = b.client.ContainerStart(ctx, created.ID, types.ContainerStartOptions{})
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// Describe maps the container's state onto a batch job status.
+func (b *dockerBackend) Describe(ctx context.Context, id string) (JobDetail, error) {
+	info, err := b.client.ContainerInspect(ctx, id)
+	if client.IsErrNotFound(err) {
+		return JobDetail{}, ErrNotFound
+	}
+	if err != nil {
+		return JobDetail{}, err
+	}
+
+	detail := JobDetail{ID: id, LogName: id}
+	state := info.State
+	switch state.Status {
+	case "created":
+		detail.Status = models.StatusQueued
+	case "running", "restarting", "paused", "removing":
+		detail.Status = models.StatusStarted
+	default:
+		detail.ExitCode = state.ExitCode
+		if state.ExitCode == 0 {
+			detail.Status = models.StatusCompleted
+			break
+		}
+		detail.Status = models.StatusErrored
+		detail.Reason = state.Error
+		if detail.Reason == "" {
+			detail.Reason = fmt.Sprintf("Exited with code %d", state.ExitCode)
+		}
+	}
+	return detail, nil
+}
+
+func (b *dockerBackend) Terminate(ctx context.Context, id string, reason string) error {
+	timeout := dockerStopTimeout
+	err := b.client.ContainerStop(ctx, id, &timeout)
+	if client.IsErrNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// Logs returns the containers' logs, by container ID.
+func (b *dockerBackend) Logs() logs.LogSource {
+	return dockerLogs{client: b.client}
+}
+
+// dockerLogs is a LogSource of containers' stdout and stderr.
+type dockerLogs struct {
+	client DockerClient
+}
+
+func (l dockerLogs) Stream(ctx context.Context, id string) (io.ReadCloser, error) {
+	return l.read(ctx, id, false)
+}
+
+func (l dockerLogs) Range(ctx context.Context, id string, from int, to int) ([]string, error) {
+	r, err := l.Stream(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return logs.ReadRange(r, from, to)
+}
+
+// Follow follows the container's logs until it exits, when docker ends
+// them.
+func (l dockerLogs) Follow(ctx context.Context, id string, finished func() bool) (io.ReadCloser, error) {
+	return l.read(ctx, id, true)
+}
+
+func (l dockerLogs) read(ctx context.Context, id string, follow bool) (io.ReadCloser, error) {
+	raw, err := l.client.ContainerLogs(ctx, id, types.ContainerLogsOptions{
+		Follow:     follow,
+		ShowStderr: true,
+		ShowStdout: true,
+	})
+	if client.IsErrNotFound(err) {
+		return nil, logs.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// docker multiplexes stdout and stderr, so strip its framing
+	r, w := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(w, w, raw)
+		raw.Close()
+		w.CloseWithError(err)
+	}()
+	return r, nil
+}
@@ -0,0 +1,230 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/aws"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+var errFakeNotFound = errors.New("not found")
+
+// fakeImageNotFound is the docker client's error for missing images.
+type fakeImageNotFound struct{}
+
+func (fakeImageNotFound) Error() string  { return "no such image" }
+func (fakeImageNotFound) NotFound() bool { return true }
+
+// fakeDocker is a docker daemon of containers in memory.
+type fakeDocker struct {
+	created    *container.Config
+	host       *container.HostConfig
+	containers map[string]types.ContainerJSON
+	logs       []byte
+	images     map[string]bool
+	pulls      []types.ImagePullOptions
+}
+
+func (f *fakeDocker) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	if !f.images[imageID] {
+		return types.ImageInspect{}, nil, fakeImageNotFound{}
+	}
+	return types.ImageInspect{ID: imageID}, nil, nil
+}
+
+func (f *fakeDocker) ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	f.pulls = append(f.pulls, options)
+	return ioutil.NopCloser(strings.NewReader("{}")), nil
+}
+
+func (f *fakeDocker) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error) {
+	f.created = config
+	f.host = hostConfig
+	f.containers["c1"] = types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			State: &types.ContainerState{Status: "created"},
+		},
+	}
+	return container.ContainerCreateCreatedBody{ID: "c1"}, nil
+}
+
+func (f *fakeDocker) ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error {
+	f.containers[containerID].State.Status = "running"
+	return nil
+}
+
+func (f *fakeDocker) ContainerStop(ctx context.Context, containerID string, timeout *time.Duration) error {
+	c, ok := f.containers[containerID]
+	if !ok {
+		return errFakeNotFound
+	}
+	c.State.Status = "exited"
+	c.State.ExitCode = 137
+	return nil
+}
+
+func (f *fakeDocker) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	c, ok := f.containers[containerID]
+	if !ok {
+		return c, errFakeNotFound
+	}
+	return c, nil
+}
+
+func (f *fakeDocker) ContainerLogs(ctx context.Context, container string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(f.logs)), nil
+}
+
+func TestDockerBackend(t *testing.T) {
+	client := &fakeDocker{containers: make(map[string]types.ContainerJSON)}
+	backend := NewDocker(Config{
+		Image:         "builder:latest",
+		DockerNetwork: "platform",
+		Binds:         []string{"/opt/Xilinx:/opt/Xilinx"},
+		Env:           []string{"XILINX_SDX=/opt/Xilinx/SDx"},
+	}, client)
+	s := New(backend, aws.ServiceConfig{Bucket: "builds"})
+
+	build := models.Build{ID: "b1", ProjectID: "p1", Tier: "large"}
+	id, err := s.RunBuild(build, "http://local/builds/b1/events", "http://local/builds/b1/reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "c1" {
+		t.Errorf("Expected container c1, got %s", id)
+	}
+	if client.created.Image != "builder:latest" || client.created.Labels["job-name"] != "build-b1-project-p1" {
+		t.Errorf("Unexpected container %+v", client.created)
+	}
+	for _, env := range []string{
+		"XILINX_SDX=/opt/Xilinx/SDx",
+		"INPUT_URL=s3://builds/" + build.InputUrl(),
+		"CALLBACK_URL=http://local/builds/b1/events",
+		"GENERATE_AFI=yes",
+	} {
+		if !inSlice(client.created.Env, env) {
+			t.Errorf("Expected %s in the environment, got %v", env, client.created.Env)
+		}
+	}
+	if client.host.NetworkMode != "platform" || !reflect.DeepEqual(client.host.Binds, []string{"/opt/Xilinx:/opt/Xilinx"}) {
+		t.Errorf("Unexpected host config %+v", client.host)
+	}
+	tier, _ := models.LookupBuildTier("large")
+	if client.host.Memory != tier.MemoryMB*1024*1024 || client.host.NanoCPUs != tier.VCPUs*1e9 {
+		t.Errorf("Expected the large tier's resources, got %+v", client.host.Resources)
+	}
+
+	detail, err := s.GetJobDetail(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Status != models.StatusStarted {
+		t.Errorf("Expected c1 to be started, got %+v", detail)
+	}
+
+	if err := s.HaltJob(id); err != nil {
+		t.Fatal(err)
+	}
+	detail, err = s.GetJobDetail(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Status != models.StatusErrored || detail.ExitCode != 137 {
+		t.Errorf("Expected c1 to have errored, got %+v", detail)
+	}
+}
+
+func TestDockerBackendSimulation(t *testing.T) {
+	client := &fakeDocker{containers: make(map[string]types.ContainerJSON)}
+	s := New(NewDocker(Config{Image: "builder:latest"}, client), aws.ServiceConfig{})
+
+	sim := models.Simulation{ID: "s1", ProjectID: "p1", Command: "test-histogram"}
+	_, err := s.RunSimulation(sim, "http://local/simulations/s1/events", "http://local/simulations/s1/reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.created.Labels["job-name"] != "simulation-s1-project-p1" {
+		t.Errorf("Unexpected job name %q", client.created.Labels["job-name"])
+	}
+	if !reflect.DeepEqual([]string(client.created.Cmd), []string{"/opt/simulate.sh"}) {
+		t.Errorf("Unexpected command %v", client.created.Cmd)
+	}
+	if !inSlice(client.created.Env, "CMD=test-histogram") {
+		t.Errorf("Expected the simulation's command in the environment, got %v", client.created.Env)
+	}
+}
+
+func TestDockerBackendLogs(t *testing.T) {
+	var framed bytes.Buffer
+	stdcopy.NewStdWriter(&framed, stdcopy.Stdout).Write([]byte("hello\n"))
+	stdcopy.NewStdWriter(&framed, stdcopy.Stderr).Write([]byte("world\n"))
+
+	s := New(NewDocker(Config{}, &fakeDocker{logs: framed.Bytes()}), aws.ServiceConfig{})
+	logs, err := s.Logs().Follow(context.Background(), "c1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logs.Close()
+
+	out, err := ioutil.ReadAll(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hello\nworld\n" {
+		t.Errorf("Unexpected logs %q", out)
+	}
+
+	lines, err := s.Logs().Range(context.Background(), "c1", 1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"world"}) {
+		t.Errorf("Unexpected lines %q", lines)
+	}
+}
+
+func inSlice(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDockerBackendPull(t *testing.T) {
+	client := &fakeDocker{
+		containers: make(map[string]types.ContainerJSON),
+		images:     map[string]bool{"builder:latest": true},
+	}
+	backend := NewDocker(Config{Image: "builder:latest", DockerRegistryAuth: "auth"}, client)
+
+	_, err := backend.Submit(context.Background(), Job{Name: "local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.pulls) != 0 {
+		t.Errorf("Expected the local image to be used, got pulls %v", client.pulls)
+	}
+
+	client.images = nil
+	_, err = backend.Submit(context.Background(), Job{Name: "pulled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.pulls) != 1 || client.pulls[0].RegistryAuth != "auth" {
+		t.Errorf("Expected the image to be pulled with the registry auth, got %v", client.pulls)
+	}
+}
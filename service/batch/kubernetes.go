@@ -0,0 +1,296 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/logs"
+	"github.com/dchest/uniuri"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// invalidKubernetesNameChars are the characters Kubernetes doesn't allow in
+// names.
+var invalidKubernetesNameChars = regexp.MustCompile("[^a-z0-9-]")
+
+// maxKubernetesNameLength is the longest name Kubernetes allows for jobs,
+// which label their pods with it.
+const maxKubernetesNameLength = 63
+
+// kubernetesSuffixChars are the characters of the random suffix which
+// makes job names unique.
+var kubernetesSuffixChars = []byte("abcdefghijklmnopqrstuvwxyz0123456789")
+
+// kubernetesJobName returns a unique, valid Kubernetes name for job.
+func kubernetesJobName(job Job) string {
+	suffix := "-" + uniuri.NewLenChars(8, kubernetesSuffixChars)
+	name := invalidKubernetesNameChars.ReplaceAllString(strings.ToLower(job.Name), "-")
+	if len(name) > maxKubernetesNameLength-len(suffix) {
+		name = name[:maxKubernetesNameLength-len(suffix)]
+	}
+	return strings.Trim(name, "-") + suffix
+}
+
+// kubernetesBackend runs batch jobs as Kubernetes Jobs, each with a
+// single pod which isn't retried. Job IDs are the Jobs' names, and log
+// names their pods' names. Jobs are labelled responsible=reco-batch.
+type kubernetesBackend struct {
+	client kubernetes.Interface
+	conf   Config
+}
+
+// NewKubernetes returns a backend running jobs in conf's namespace of the
+// cluster client talks to.
+func NewKubernetes(conf Config, client kubernetes.Interface) Backend {
+	return &kubernetesBackend{client: client, conf: conf}
+}
+
+// NewKubernetesCluster returns a backend running jobs on the cluster in
+// conf's kubeconfig, or the cluster we're running in when it has none.
+func NewKubernetesCluster(conf Config) (Backend, error) {
+	var (
+		restConf *rest.Config
+		err      error
+	)
+	if conf.KubernetesConfig == "" {
+		restConf, err = rest.InClusterConfig()
+	} else {
+		restConf, err = clientcmd.BuildConfigFromFlags("", conf.KubernetesConfig)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure kubernetes client: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(restConf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure kubernetes client: %v", err)
+	}
+	return NewKubernetes(conf, client), nil
+}
+
+// kubernetesEnv passes job's environment to its pod, after the extra
+// variables configured for all jobs.
+func (b *kubernetesBackend) kubernetesEnv(job Job) []corev1.EnvVar {
+	env := []corev1.EnvVar{}
+	for _, kv := range b.conf.Env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		env = append(env, corev1.EnvVar{Name: parts[0], Value: parts[1]})
+	}
+
+	names := make([]string, 0, len(job.Env))
+	for name := range job.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, corev1.EnvVar{Name: name, Value: job.Env[name]})
+	}
+	return env
+}
+
+// kubernetesVolumes mounts the configured host:container binds.
+func (b *kubernetesBackend) kubernetesVolumes() ([]corev1.Volume, []corev1.VolumeMount) {
+	volumes := []corev1.Volume{}
+	mounts := []corev1.VolumeMount{}
+	for i, bind := range b.conf.Binds {
+		parts := strings.SplitN(bind, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := fmt.Sprintf("bind-%d", i)
+		volumes = append(volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: parts[0]},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: name, MountPath: parts[1]})
+	}
+	return volumes, mounts
+}
+
+func (b *kubernetesBackend) Submit(ctx context.Context, job Job) (string, error) {
+	resources := corev1.ResourceList{}
+	if job.MemoryMB > 0 {
+		resources[corev1.ResourceMemory] = *resource.NewQuantity(job.MemoryMB*1024*1024, resource.BinarySI)
+	}
+	if job.VCPUs > 0 {
+		resources[corev1.ResourceCPU] = *resource.NewQuantity(job.VCPUs, resource.DecimalSI)
+	}
+	annotations := map[string]string{"reco-job-name": job.Name}
+	if b.conf.KubernetesIAMRole != "" {
+		annotations["iam.amazonaws.com/role"] = b.conf.KubernetesIAMRole
+	}
+	volumes, mounts := b.kubernetesVolumes()
+	// failed jobs are retried by us, if at all, rather than Kubernetes
+	backoffLimit := int32(0)
+
+	created, err := b.client.BatchV1().Jobs(b.conf.KubernetesNamespace).Create(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        kubernetesJobName(job),
+			Labels:      map[string]string{"responsible": "reco-batch"},
+			Annotations: annotations,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{"responsible": "reco-batch"},
+					Annotations: annotations,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Volumes:       volumes,
+					Containers: []corev1.Container{{
+						Name:         "job",
+						Image:        b.conf.Image,
+						Args:         job.Command,
+						Env:          b.kubernetesEnv(job),
+						VolumeMounts: mounts,
+						Resources:    corev1.ResourceRequirements{Requests: resources},
+					}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return created.Name, nil
+}
+
+// Describe maps the phase of the job's latest pod onto a batch job
+// status, or the job's own status before it has a pod.
+func (b *kubernetesBackend) Describe(ctx context.Context, id string) (JobDetail, error) {
+	job, err := b.client.BatchV1().Jobs(b.conf.KubernetesNamespace).Get(id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return JobDetail{}, ErrNotFound
+	}
+	if err != nil {
+		return JobDetail{}, err
+	}
+
+	pods, err := b.client.CoreV1().Pods(b.conf.KubernetesNamespace).List(metav1.ListOptions{
+		LabelSelector: "job-name=" + id,
+	})
+	if err != nil {
+		return JobDetail{}, err
+	}
+
+	detail := JobDetail{ID: id}
+	if len(pods.Items) == 0 {
+		detail.Status = models.StatusQueued
+		for _, cond := range job.Status.Conditions {
+			if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+				detail.Status = models.StatusErrored
+				detail.Reason = cond.Message
+			}
+		}
+		return detail, nil
+	}
+
+	pod := pods.Items[0]
+	for _, p := range pods.Items[1:] {
+		if p.CreationTimestamp.After(pod.CreationTimestamp.Time) {
+			pod = p
+		}
+	}
+	detail.LogName = pod.Name
+
+	switch pod.Status.Phase {
+	case corev1.PodPending:
+		detail.Status = models.StatusQueued
+	case corev1.PodSucceeded:
+		detail.Status = models.StatusCompleted
+	case corev1.PodFailed:
+		detail.Status = models.StatusErrored
+		detail.Reason = pod.Status.Message
+		if pod.Status.Reason != "" {
+			// e.g. Evicted, when the node ran out of resources
+			detail.Reason = pod.Status.Reason + ": " + pod.Status.Message
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.State.Terminated == nil {
+				continue
+			}
+			detail.ExitCode = int(cs.State.Terminated.ExitCode)
+			if detail.Reason == "" {
+				detail.Reason = fmt.Sprintf("%s: exited with code %d", cs.State.Terminated.Reason, detail.ExitCode)
+			}
+		}
+	default:
+		detail.Status = models.StatusStarted
+	}
+	return detail, nil
+}
+
+// Terminate deletes the job, and its pods.
+func (b *kubernetesBackend) Terminate(ctx context.Context, id string, reason string) error {
+	propagation := metav1.DeletePropagationBackground
+	err := b.client.BatchV1().Jobs(b.conf.KubernetesNamespace).Delete(id, &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// Logs returns the logs of jobs' pods, by pod name.
+func (b *kubernetesBackend) Logs() logs.LogSource {
+	return kubernetesLogs{
+		podLogs: func(ctx context.Context, pod string, follow bool) (io.ReadCloser, error) {
+			return b.client.CoreV1().Pods(b.conf.KubernetesNamespace).
+				GetLogs(pod, &corev1.PodLogOptions{Follow: follow}).
+				Context(ctx).
+				Stream()
+		},
+	}
+}
+
+// kubernetesLogs is a LogSource of pods' logs.
+type kubernetesLogs struct {
+	// podLogs reads the logs of pod, following them if follow is set.
+	podLogs func(ctx context.Context, pod string, follow bool) (io.ReadCloser, error)
+}
+
+func (l kubernetesLogs) Stream(ctx context.Context, pod string) (io.ReadCloser, error) {
+	return l.read(ctx, pod, false)
+}
+
+func (l kubernetesLogs) Range(ctx context.Context, pod string, from int, to int) ([]string, error) {
+	r, err := l.Stream(ctx, pod)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return logs.ReadRange(r, from, to)
+}
+
+// Follow follows the pod's logs until it exits, when Kubernetes ends
+// them.
+func (l kubernetesLogs) Follow(ctx context.Context, pod string, finished func() bool) (io.ReadCloser, error) {
+	return l.read(ctx, pod, true)
+}
+
+func (l kubernetesLogs) read(ctx context.Context, pod string, follow bool) (io.ReadCloser, error) {
+	r, err := l.podLogs(ctx, pod, follow)
+	if apierrors.IsNotFound(err) {
+		return nil, logs.ErrNotFound
+	}
+	return r, err
+}
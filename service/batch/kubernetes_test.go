@@ -0,0 +1,199 @@
+package batch
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubernetesJobName(t *testing.T) {
+	name := kubernetesJobName(Job{Name: "build-B1_" + strings.Repeat("x", 100)})
+	if len(name) > maxKubernetesNameLength {
+		t.Errorf("Expected at most %d characters, got %d", maxKubernetesNameLength, len(name))
+	}
+	if !strings.HasPrefix(name, "build-b1-xxx") || invalidKubernetesNameChars.MatchString(name) {
+		t.Errorf("Unexpected name %s", name)
+	}
+	if kubernetesJobName(Job{Name: "simulation"}) == kubernetesJobName(Job{Name: "simulation"}) {
+		t.Errorf("Expected job names to be unique")
+	}
+}
+
+func TestKubernetesBackend(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	conf := Config{
+		Image:               "builder:latest",
+		Binds:               []string{"/opt/Xilinx:/opt/Xilinx"},
+		Env:                 []string{"XILINX_SDX=/opt/Xilinx/SDx"},
+		KubernetesNamespace: "builds",
+		KubernetesIAMRole:   "arn:aws:iam::123:role/builder",
+	}
+	s := New(NewKubernetes(conf, client), aws.ServiceConfig{Bucket: "builds"})
+
+	build := models.Build{ID: "b1", ProjectID: "p1", Tier: "large"}
+	id, err := s.RunBuild(build, "http://local/builds/b1/events", "http://local/builds/b1/reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := client.BatchV1().Jobs("builds").Get(id, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Annotations["reco-job-name"] != "build-b1-project-p1" {
+		t.Errorf("Unexpected annotations %v", job.Annotations)
+	}
+	pod := job.Spec.Template
+	if pod.Annotations["iam.amazonaws.com/role"] != conf.KubernetesIAMRole || pod.Spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("Unexpected pod template %+v", pod)
+	}
+	container := pod.Spec.Containers[0]
+	if container.Image != "builder:latest" || container.VolumeMounts[0].MountPath != "/opt/Xilinx" {
+		t.Errorf("Unexpected container %+v", container)
+	}
+	for _, env := range []corev1.EnvVar{
+		{Name: "XILINX_SDX", Value: "/opt/Xilinx/SDx"},
+		{Name: "INPUT_URL", Value: "s3://builds/" + build.InputUrl()},
+		{Name: "CALLBACK_URL", Value: "http://local/builds/b1/events"},
+		{Name: "GENERATE_AFI", Value: "yes"},
+	} {
+		found := false
+		for _, e := range container.Env {
+			found = found || reflect.DeepEqual(e, env)
+		}
+		if !found {
+			t.Errorf("Expected %s=%s in the environment, got %v", env.Name, env.Value, container.Env)
+		}
+	}
+	tier, _ := models.LookupBuildTier("large")
+	memory := container.Resources.Requests[corev1.ResourceMemory]
+	cpu := container.Resources.Requests[corev1.ResourceCPU]
+	if memory.Value() != tier.MemoryMB*1024*1024 || cpu.Value() != tier.VCPUs {
+		t.Errorf("Expected the large tier's resources, got %v", container.Resources.Requests)
+	}
+
+	// without a pod, the job is queued
+	detail, err := s.GetJobDetail(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Status != models.StatusQueued {
+		t.Errorf("Expected %s to be queued, got %+v", id, detail)
+	}
+
+	_, err = client.CoreV1().Pods("builds").Create(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   id + "-abcde",
+			Labels: map[string]string{"job-name": id},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	detail, err = s.GetJobDetail(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Status != models.StatusStarted || detail.LogName != id+"-abcde" {
+		t.Errorf("Expected %s to be started, got %+v", id, detail)
+	}
+
+	if err := s.HaltJob(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetJobDetail(id); err != ErrNotFound {
+		t.Errorf("Expected %s to be deleted, got %v", id, err)
+	}
+}
+
+func TestKubernetesPodPhases(t *testing.T) {
+	cases := []struct {
+		phase    corev1.PodPhase
+		state    corev1.ContainerState
+		status   string
+		exitCode int
+	}{
+		{phase: corev1.PodPending, status: models.StatusQueued},
+		{phase: corev1.PodRunning, status: models.StatusStarted},
+		{phase: corev1.PodSucceeded, status: models.StatusCompleted},
+		{
+			phase:    corev1.PodFailed,
+			state:    corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"}},
+			status:   models.StatusErrored,
+			exitCode: 2,
+		},
+	}
+
+	for _, c := range cases {
+		client := fake.NewSimpleClientset()
+		backend := NewKubernetes(Config{KubernetesNamespace: "builds"}, client)
+		id, err := backend.Submit(context.Background(), Job{Name: "simulation"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.CoreV1().Pods("builds").Create(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   id + "-abcde",
+				Labels: map[string]string{"job-name": id},
+			},
+			Status: corev1.PodStatus{
+				Phase:             c.phase,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "job", State: c.state}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		detail, err := backend.Describe(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if detail.Status != c.status || detail.ExitCode != c.exitCode {
+			t.Errorf("%s: expected %s with exit code %d, got %+v", c.phase, c.status, c.exitCode, detail)
+		}
+	}
+}
+
+func TestKubernetesLogs(t *testing.T) {
+	// the fake clientset can't stream logs
+	source := kubernetesLogs{
+		podLogs: func(ctx context.Context, pod string, follow bool) (io.ReadCloser, error) {
+			if follow {
+				return ioutil.NopCloser(strings.NewReader("following " + pod + "\n")), nil
+			}
+			return ioutil.NopCloser(strings.NewReader("logs of\n" + pod + "\n")), nil
+		},
+	}
+
+	logs, err := source.Follow(context.Background(), "job-abcde", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logs.Close()
+	out, err := ioutil.ReadAll(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "following job-abcde\n" {
+		t.Errorf("Unexpected logs %q", out)
+	}
+
+	lines, err := source.Range(context.Background(), "job-abcde", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"job-abcde"}) {
+		t.Errorf("Unexpected lines %q", lines)
+	}
+}
@@ -0,0 +1,186 @@
+// Package batchretry retries batch jobs which fail because of the
+// infrastructure running them, rather than their input.
+package batchretry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/batch"
+)
+
+// Config is the retry policy of batch jobs.
+type Config struct {
+	// MaxAttempts is how many times a batch job is run before it's marked
+	// as errored. 1 disables retries.
+	MaxAttempts int `env:"RECO_BATCH_MAX_ATTEMPTS" envDefault:"3"`
+}
+
+// Failure is why a batch job failed.
+type Failure int
+
+const (
+	// NotFailed jobs haven't failed, as far as their backend knows.
+	NotFailed Failure = iota
+	// UserFailure jobs failed because of their input, such as code which
+	// doesn't compile, and would fail again if retried.
+	UserFailure
+	// InfraFailure jobs failed because of the infrastructure running
+	// them, and may succeed if retried.
+	InfraFailure
+)
+
+// infraReasons are parts of the reasons backends give jobs which failed
+// because of their infrastructure.
+var infraReasons = []string{
+	"Host EC2",                    // instance terminated, including spot reclaims
+	"Spot",                        // spot instance interruptions
+	"CannotPullContainerError",    // container image pull failures
+	"CannotStartContainerError",   // container runtime failures
+	"CannotCreateContainerError",  // container runtime failures
+	"CannotInspectContainerError", // container runtime failures
+	"DockerTimeoutError",          // container runtime failures
+	"ResourceInitializationError", // volume and network set up failures
+	"Task failed to start",        // ECS placement failures
+	"Evicted",                     // kubernetes pods evicted from their node
+	"NodeLost",                    // kubernetes nodes which stopped responding
+}
+
+// Classify returns why job failed, and the reason its backend gave. Jobs
+// which failed with reasons matching no known infrastructure failure,
+// including those whose container exited non-zero, are user failures.
+func Classify(job batch.JobDetail) (Failure, string) {
+	if job.Status != models.StatusErrored {
+		return NotFailed, ""
+	}
+
+	reasons := job.Reasons
+	if len(reasons) == 0 {
+		reasons = []string{job.Reason}
+	}
+
+	reason := ""
+	for _, r := range reasons {
+		if r == "" {
+			continue
+		}
+		if reason == "" {
+			reason = r
+		}
+		for _, infra := range infraReasons {
+			if strings.Contains(r, infra) {
+				return InfraFailure, r
+			}
+		}
+	}
+	return UserFailure, reason
+}
+
+// Backend describes and terminates batch jobs, as batch.Backend does.
+type Backend interface {
+	Describe(ctx context.Context, id string) (batch.JobDetail, error)
+	Terminate(ctx context.Context, id string, reason string) error
+}
+
+// Retrier retries batch jobs which failed because of their infrastructure,
+// and marks those which failed otherwise, or too many times, as errored.
+type Retrier struct {
+	Backend   Backend
+	BatchRepo models.BatchRepo
+	// Resubmit submits what a batch job runs again, with the same input,
+	// by the kind and ID BatchRepo.Owner returns, and returns the new
+	// batch ID.
+	Resubmit    func(kind string, id string) (string, error)
+	MaxAttempts int
+}
+
+// RetryFailed checks the batch jobs which have been active since
+// sinceTime with their backend, and handles those which failed without
+// reporting it. Jobs which fail of their own accord report ERRORED
+// themselves, with the compiler's exit code, and are never retried.
+func (r *Retrier) RetryFailed(ctx context.Context, sinceTime time.Time) error {
+	batchJobs, err := r.BatchRepo.ActiveJobs(sinceTime)
+	if err != nil {
+		return err
+	}
+
+	for _, batchJob := range batchJobs {
+		detail, err := r.Backend.Describe(ctx, batchJob.BatchID)
+		if err == batch.ErrNotFound {
+			continue
+		}
+		if err == nil {
+			err = r.Handle(batchJob, detail)
+		}
+		if err != nil {
+			log.WithError(err).
+				WithFields(log.Fields{"batch_id": batchJob.BatchID}).
+				Error("Couldn't handle failed batch job")
+		}
+	}
+	return nil
+}
+
+// Handle retries batchJob if detail, its backend's description of it,
+// shows it failed because of its infrastructure and it has attempts left,
+// and marks it as errored if it failed otherwise.
+func (r *Retrier) Handle(batchJob models.BatchJob, detail batch.JobDetail) error {
+	failure, reason := Classify(detail)
+	if failure == NotFailed {
+		return nil
+	}
+	return r.handle(batchJob, failure, reason)
+}
+
+// handle records batchJob's failed attempt, and retries it if failure is
+// worth retrying and it has attempts left.
+func (r *Retrier) handle(batchJob models.BatchJob, failure Failure, reason string) error {
+	attempt := models.BatchJobAttempt{
+		Attempt: len(batchJob.Attempts) + 1,
+		BatchID: batchJob.BatchID,
+		Reason:  reason,
+	}
+
+	if failure == InfraFailure && attempt.Attempt < r.MaxAttempts {
+		kind, id, err := r.BatchRepo.Owner(batchJob)
+		if err != nil {
+			return err
+		}
+		batchID, err := r.Resubmit(kind, id)
+		if err == nil {
+			attempt.Retried = true
+			added, err := r.BatchRepo.AddAttempt(batchJob, attempt, batchID, models.BatchJobEvent{
+				Timestamp: time.Now(),
+				Status:    models.StatusQueued,
+				Message: fmt.Sprintf("Retrying after infrastructure failure: %s (attempt %d of %d)",
+					reason, attempt.Attempt+1, r.MaxAttempts),
+			})
+			if err == nil && !added {
+				// the failure was handled first elsewhere, e.g. by the
+				// runner waiting on the job, and only that resubmission
+				// is tracked
+				err = r.Backend.Terminate(context.Background(), batchID, "Duplicate retry")
+			}
+			return err
+		}
+		log.WithError(err).
+			WithFields(log.Fields{"kind": kind, "id": id}).
+			Error("Couldn't resubmit batch job")
+	}
+
+	message := "Job failed"
+	if reason != "" {
+		message = "Job failed: " + reason
+	}
+	_, err := r.BatchRepo.AddAttempt(batchJob, attempt, "", models.BatchJobEvent{
+		Timestamp: time.Now(),
+		Status:    models.StatusErrored,
+		Message:   message,
+	})
+	return err
+}
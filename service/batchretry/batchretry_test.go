@@ -0,0 +1,220 @@
+package batchretry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/batch"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		name    string
+		job     batch.JobDetail
+		failure Failure
+		reason  string
+	}{
+		{
+			name:    "running",
+			job:     batch.JobDetail{Status: models.StatusStarted},
+			failure: NotFailed,
+		},
+		{
+			name: "host terminated",
+			job: batch.JobDetail{
+				Status: models.StatusErrored,
+				Reason: "Host EC2 (instance i-0123456789) terminated.",
+			},
+			failure: InfraFailure,
+			reason:  "Host EC2 (instance i-0123456789) terminated.",
+		},
+		{
+			name: "image pull",
+			job: batch.JobDetail{
+				Status: models.StatusErrored,
+				Reason: "Essential container in task exited",
+				Reasons: []string{
+					"Essential container in task exited",
+					"",
+					"CannotPullContainerError: manifest unknown",
+				},
+			},
+			failure: InfraFailure,
+			reason:  "CannotPullContainerError: manifest unknown",
+		},
+		{
+			name: "evicted",
+			job: batch.JobDetail{
+				Status: models.StatusErrored,
+				Reason: "Evicted: The node was low on resource: memory.",
+			},
+			failure: InfraFailure,
+			reason:  "Evicted: The node was low on resource: memory.",
+		},
+		{
+			name: "non-zero exit",
+			job: batch.JobDetail{
+				Status:   models.StatusErrored,
+				Reason:   "Essential container in task exited",
+				ExitCode: 1,
+			},
+			failure: UserFailure,
+			reason:  "Essential container in task exited",
+		},
+	}
+
+	for _, c := range cases {
+		failure, reason := Classify(c.job)
+		if failure != c.failure || reason != c.reason {
+			t.Errorf("%s: expected (%v, %q), got (%v, %q)", c.name, c.failure, c.reason, failure, reason)
+		}
+	}
+}
+
+type fakeBackend struct {
+	jobs map[string]batch.JobDetail
+
+	mu         sync.Mutex
+	terminated []string
+}
+
+func (f *fakeBackend) Describe(ctx context.Context, id string) (batch.JobDetail, error) {
+	job, found := f.jobs[id]
+	if !found {
+		return batch.JobDetail{}, batch.ErrNotFound
+	}
+	return job, nil
+}
+
+func (f *fakeBackend) Terminate(ctx context.Context, id string, reason string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.terminated = append(f.terminated, id)
+	return nil
+}
+
+func TestRetryFailed(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	b := models.NewMockBatchRepo(mockCtrl)
+
+	reclaimed := "Host EC2 (instance i-0123456789) terminated."
+	backend := &fakeBackend{jobs: map[string]batch.JobDetail{
+		"running":   {Status: models.StatusStarted},
+		"reclaimed": {Status: models.StatusErrored, Reason: reclaimed},
+		"exhausted": {Status: models.StatusErrored, Reason: reclaimed},
+	}}
+
+	batchJobs := []models.BatchJob{
+		{ID: 1, BatchID: "running"},
+		{ID: 2, BatchID: "reclaimed"},
+		{ID: 3, BatchID: "exhausted", Attempts: []models.BatchJobAttempt{
+			{Attempt: 1, Retried: true},
+		}},
+		{ID: 4, BatchID: "forgotten"},
+	}
+
+	var resubmitted []string
+	retrier := &Retrier{
+		Backend:   backend,
+		BatchRepo: b,
+		Resubmit: func(kind string, id string) (string, error) {
+			resubmitted = append(resubmitted, kind+"/"+id)
+			return "retried", nil
+		},
+		MaxAttempts: 2,
+	}
+
+	sinceTime := time.Unix(0, 0)
+	b.EXPECT().ActiveJobs(sinceTime).Return(batchJobs, nil)
+
+	// the reclaimed job is resubmitted as its first retry
+	b.EXPECT().Owner(batchJobs[1]).Return("build", "build-1", nil)
+	b.EXPECT().AddAttempt(batchJobs[1], models.BatchJobAttempt{
+		Attempt: 1,
+		BatchID: "reclaimed",
+		Reason:  reclaimed,
+		Retried: true,
+	}, "retried", gomock.Any()).Return(true, nil)
+
+	// the exhausted job has no attempts left, so errors
+	b.EXPECT().AddAttempt(batchJobs[2], models.BatchJobAttempt{
+		Attempt: 2,
+		BatchID: "exhausted",
+		Reason:  reclaimed,
+	}, "", gomock.Any()).Return(true, nil)
+
+	err := retrier.RetryFailed(context.Background(), sinceTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resubmitted) != 1 || resubmitted[0] != "build/build-1" {
+		t.Errorf("Expected build/build-1 to be resubmitted, got %v", resubmitted)
+	}
+}
+
+func TestHandleConcurrently(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	b := models.NewMockBatchRepo(mockCtrl)
+	backend := &fakeBackend{}
+
+	var mu sync.Mutex
+	var resubmitted []string
+	retrier := &Retrier{
+		Backend:   backend,
+		BatchRepo: b,
+		Resubmit: func(kind string, id string) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			batchID := fmt.Sprintf("retried-%d", len(resubmitted)+1)
+			resubmitted = append(resubmitted, batchID)
+			return batchID, nil
+		},
+		MaxAttempts: 2,
+	}
+
+	batchJob := models.BatchJob{ID: 1, BatchID: "reclaimed"}
+	detail := batch.JobDetail{Status: models.StatusErrored, Reason: "Host EC2 (instance i-0123456789) terminated."}
+
+	// as with the row lock, only the first attempt is recorded
+	var tracked string
+	b.EXPECT().Owner(batchJob).Return("build", "build-1", nil).Times(2)
+	b.EXPECT().AddAttempt(batchJob, gomock.Any(), gomock.Any(), gomock.Any()).Times(2).DoAndReturn(
+		func(_ models.BatchJob, _ models.BatchJobAttempt, batchID string, _ models.BatchJobEvent) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if tracked != "" {
+				return false, nil
+			}
+			tracked = batchID
+			return true, nil
+		})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := retrier.Handle(batchJob, detail); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(resubmitted) != 2 {
+		t.Fatalf("Expected both handlers to resubmit, got %v", resubmitted)
+	}
+	if len(backend.terminated) != 1 || backend.terminated[0] == tracked {
+		t.Errorf("Expected the untracked resubmission of %v to be terminated, got %v", resubmitted, backend.terminated)
+	}
+}
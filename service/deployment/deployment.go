@@ -7,7 +7,11 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/ReconfigureIO/platform/models"
 	awsservice "github.com/ReconfigureIO/platform/service/aws"
@@ -27,6 +31,7 @@ type ContainerConfig struct {
 type LogsConfig struct {
 	Group  string `json:"group"`
 	Prefix string `json:"prefix"`
+	Region string `json:"region,omitempty"`
 }
 
 type BuildConfig struct {
@@ -42,10 +47,24 @@ type Deployment struct {
 }
 
 type service struct {
-	session *session.Session
-	Conf    ServiceConfig
+	Conf ServiceConfig
+
+	mu       sync.Mutex
+	sessions map[string]*session.Session
 }
 
+// defaultRegion is the home region when none is configured.
+const defaultRegion = "us-east-1"
+
+var (
+	// ErrUnknownRegion is returned for deployments to regions which
+	// aren't allowed.
+	ErrUnknownRegion = errors.New("unknown deployment region")
+	// ErrUnknownInstanceType is returned for deployments to instance types
+	// which aren't allowed.
+	ErrUnknownInstanceType = errors.New("unknown deployment instance type")
+)
+
 type ServiceConfig struct {
 	LogGroup      string `env:"RECO_DEPLOY_LOG_GROUP" envDefault:"/reconfigureio/deployments"`
 	Image         string `env:"RECO_DEPLOY_IMAGE" envDefault:"reconfigureio/docker-aws-fpga-runtime:latest"`
@@ -53,14 +72,157 @@ type ServiceConfig struct {
 	Bucket        string `env:"RECO_DEPLOY_BUCKET" envDefault:"reconfigureio-builds"`
 	Subnet        string `env:"RECO_DEPLOY_SUBNET" envDefault:"subnet-fa2a9c9e"`
 	SecurityGroup string `env:"RECO_DEPLOY_SG" envDefault:"sg-7fbfbe0c"`
+	// Region is the home region, whose AMI, subnet and security group are
+	// the ones above. Deployments which don't pick a region run here, and
+	// deployments in every region log here.
+	Region string `env:"RECO_DEPLOY_REGION" envDefault:"us-east-1"`
+	// Regions and InstanceTypes are what deployments may pick from.
+	Regions             []string `env:"RECO_DEPLOY_REGIONS" envDefault:"us-east-1"`
+	InstanceTypes       []string `env:"RECO_DEPLOY_INSTANCE_TYPES" envDefault:"f1.2xlarge,f1.4xlarge,f1.16xlarge"`
+	DefaultInstanceType string   `env:"RECO_DEPLOY_INSTANCE_TYPE" envDefault:"f1.2xlarge"`
+	// RegionAMIs, RegionSubnets, RegionSecurityGroups and SpotZones
+	// configure each region as region=value, e.g. eu-west-1=ami-123.
+	RegionAMIs           []string `env:"RECO_DEPLOY_REGION_AMIS"`
+	RegionSubnets        []string `env:"RECO_DEPLOY_REGION_SUBNETS"`
+	RegionSecurityGroups []string `env:"RECO_DEPLOY_REGION_SGS"`
+	SpotZones            []string `env:"RECO_DEPLOY_SPOT_ZONES" envDefault:"us-east-1=us-east-1d"`
+	// Backends are the backends deployments can run on, and DefaultBackend
+	// the one used when neither the deployment nor its project pick one.
+	Backends       []string `env:"RECO_DEPLOY_BACKENDS" envDefault:"ec2-f1"`
+	DefaultBackend string   `env:"RECO_DEPLOY_DEFAULT_BACKEND" envDefault:"ec2-f1"`
+	// DockerNetwork is the network docker deployments are attached to.
+	DockerNetwork string `env:"RECO_DEPLOY_DOCKER_NETWORK"`
+	// Hosts are the docker daemons of the host pool, as name=address,
+	// e.g. fpga1=tcp://fpga1:2376.
+	Hosts []string `env:"RECO_DEPLOY_HOSTS"`
 }
 
 func newService(conf ServiceConfig) *service {
-	s := service{Conf: conf}
-	s.session = session.Must(session.NewSession(aws.NewConfig().WithRegion("us-east-1")))
+	s := service{
+		Conf:     conf,
+		sessions: make(map[string]*session.Session),
+	}
 	return &s
 }
 
+// RegionConfig is where in a region deployments run.
+type RegionConfig struct {
+	AMI           string
+	Subnet        string
+	SecurityGroup string
+	// SpotZone is the availability zone of spot instances, if any.
+	SpotZone string
+}
+
+// HomeRegion returns the home region.
+func (s *ServiceConfig) HomeRegion() string {
+	if s.Region == "" {
+		return defaultRegion
+	}
+	return s.Region
+}
+
+// regionValue finds the value for region in a list of region=value.
+func regionValue(values []string, region string) (string, bool) {
+	for _, v := range values {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 && parts[0] == region {
+			return parts[1], true
+		}
+	}
+	return "", false
+}
+
+// RegionConfig returns the configuration of a region. The home region
+// defaults to AMI, Subnet and SecurityGroup.
+func (s *ServiceConfig) RegionConfig(region string) RegionConfig {
+	rc := RegionConfig{}
+	if region == s.HomeRegion() {
+		rc = RegionConfig{AMI: s.AMI, Subnet: s.Subnet, SecurityGroup: s.SecurityGroup}
+	}
+	if v, ok := regionValue(s.RegionAMIs, region); ok {
+		rc.AMI = v
+	}
+	if v, ok := regionValue(s.RegionSubnets, region); ok {
+		rc.Subnet = v
+	}
+	if v, ok := regionValue(s.RegionSecurityGroups, region); ok {
+		rc.SecurityGroup = v
+	}
+	if v, ok := regionValue(s.SpotZones, region); ok {
+		rc.SpotZone = v
+	}
+	return rc
+}
+
+// Complete returns if deployments can run in the region, which needs an
+// AMI, subnet and security group of its own, as they're region-scoped.
+func (rc RegionConfig) Complete() bool {
+	return rc.AMI != "" && rc.Subnet != "" && rc.SecurityGroup != ""
+}
+
+// ValidateRegions checks the home region, and every region deployments
+// may pick, is configured.
+func (s *ServiceConfig) ValidateRegions() error {
+	regions := append([]string{s.HomeRegion()}, s.Regions...)
+	for _, region := range regions {
+		if !s.RegionConfig(region).Complete() {
+			return fmt.Errorf("deployment region %s needs an AMI, subnet and security group", region)
+		}
+	}
+	return nil
+}
+
+// Placement returns the region and instance type of a deployment asking
+// for them, filling in the defaults, and checking they're allowed.
+func (s *ServiceConfig) Placement(region string, instanceType string) (string, string, error) {
+	if region == "" {
+		region = s.HomeRegion()
+	} else if region != s.HomeRegion() && !inSlice(s.Regions, region) {
+		return "", "", ErrUnknownRegion
+	} else if !s.RegionConfig(region).Complete() {
+		return "", "", ErrUnknownRegion
+	}
+	if instanceType == "" {
+		instanceType = s.DefaultInstanceType
+	} else if !inSlice(s.InstanceTypes, instanceType) {
+		return "", "", ErrUnknownInstanceType
+	}
+	return region, instanceType, nil
+}
+
+// region returns the region of a deployment, which is the home region for
+// those made before deployments had regions.
+func (s *service) region(deployment models.Deployment) string {
+	if deployment.Region == "" {
+		return s.Conf.HomeRegion()
+	}
+	return deployment.Region
+}
+
+// instanceType returns the instance type of a deployment.
+func (s *service) instanceType(deployment models.Deployment) string {
+	if deployment.InstanceType != "" {
+		return deployment.InstanceType
+	}
+	if s.Conf.DefaultInstanceType != "" {
+		return s.Conf.DefaultInstanceType
+	}
+	return "f1.2xlarge"
+}
+
+// session returns the session for a region.
+func (s *service) session(region string) *session.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[region]
+	if !ok {
+		sess = session.Must(session.NewSession(aws.NewConfig().WithRegion(region)))
+		s.sessions[region] = sess
+	}
+	return sess
+}
+
 func New(conf ServiceConfig) Service {
 	return newService(conf)
 }
@@ -93,6 +255,7 @@ func (s *ServiceConfig) ContainerConfig(deployment models.Deployment, callbackUr
 		Logs: LogsConfig{
 			Group:  s.LogGroup,
 			Prefix: fmt.Sprintf("deployment-%s", deployment.ID),
+			Region: s.HomeRegion(),
 		},
 		Build: BuildConfig{
 			ArtifactUrl: fmt.Sprintf("s3://%s/%s", s.Bucket, deployment.Build.ArtifactUrl()),
@@ -110,23 +273,23 @@ func (d Deployment) String() (string, error) {
 	return buff.String(), err
 }
 
-func (s *service) runSpotInstance(ctx context.Context, encodedConfig string, dryRun bool) (string, error) {
-	ec2Session := ec2.New(s.session)
+func (s *service) runSpotInstance(ctx context.Context, deployment models.Deployment, encodedConfig string, dryRun bool) (string, error) {
+	region := s.region(deployment)
+	rc := s.Conf.RegionConfig(region)
+	instanceType := s.instanceType(deployment)
+	ec2Session := ec2.New(s.session(region))
 
 	launch := ec2.RequestSpotLaunchSpecification{
-		ImageId:      aws.String(s.Conf.AMI),
-		InstanceType: aws.String("f1.2xlarge"),
+		ImageId:      aws.String(rc.AMI),
+		InstanceType: aws.String(instanceType),
 		UserData:     aws.String(encodedConfig),
-		Placement: &ec2.SpotPlacement{
-			AvailabilityZone: aws.String("us-east-1d"),
-		},
 		NetworkInterfaces: []*ec2.InstanceNetworkInterfaceSpecification{
 			&ec2.InstanceNetworkInterfaceSpecification{
 				DeviceIndex:              aws.Int64(0),
 				AssociatePublicIpAddress: aws.Bool(true),
 				DeleteOnTermination:      aws.Bool(true),
-				SubnetId:                 aws.String(s.Conf.Subnet),
-				Groups:                   aws.StringSlice([]string{s.Conf.SecurityGroup}),
+				SubnetId:                 aws.String(rc.Subnet),
+				Groups:                   aws.StringSlice([]string{rc.SecurityGroup}),
 			},
 		},
 		IamInstanceProfile: &ec2.IamInstanceProfileSpecification{
@@ -134,11 +297,20 @@ func (s *service) runSpotInstance(ctx context.Context, encodedConfig string, dry
 		},
 	}
 
+	if rc.SpotZone != "" {
+		launch.Placement = &ec2.SpotPlacement{
+			AvailabilityZone: aws.String(rc.SpotZone),
+		}
+	}
+
+	// bid the same for each FPGA of larger instances
+	spotPrice := strconv.FormatFloat(1.20*float64(models.InstanceWeight(instanceType)), 'f', 2, 64)
+
 	cfg := ec2.RequestSpotInstancesInput{
 		DryRun:              aws.Bool(dryRun),
 		InstanceCount:       aws.Int64(1),
 		LaunchSpecification: &launch,
-		SpotPrice:           aws.String("1.20"),
+		SpotPrice:           aws.String(spotPrice),
 		Type:                aws.String("one-time"),
 	}
 
@@ -152,14 +324,16 @@ func (s *service) runSpotInstance(ctx context.Context, encodedConfig string, dry
 	return InstanceId, nil
 }
 
-func (s *service) runInstance(ctx context.Context, encodedConfig string, dryRun bool) (string, error) {
-	ec2Session := ec2.New(s.session)
+func (s *service) runInstance(ctx context.Context, deployment models.Deployment, encodedConfig string, dryRun bool) (string, error) {
+	region := s.region(deployment)
+	rc := s.Conf.RegionConfig(region)
+	ec2Session := ec2.New(s.session(region))
 
 	cfg := ec2.RunInstancesInput{
 		DryRun:                            aws.Bool(dryRun),
-		ImageId:                           aws.String(s.Conf.AMI),
+		ImageId:                           aws.String(rc.AMI),
 		InstanceInitiatedShutdownBehavior: aws.String("terminate"),
-		InstanceType:                      aws.String("f1.2xlarge"),
+		InstanceType:                      aws.String(s.instanceType(deployment)),
 		MaxCount:                          aws.Int64(1),
 		MinCount:                          aws.Int64(1),
 		UserData:                          aws.String(encodedConfig),
@@ -168,8 +342,8 @@ func (s *service) runInstance(ctx context.Context, encodedConfig string, dryRun
 				DeviceIndex:              aws.Int64(0),
 				AssociatePublicIpAddress: aws.Bool(true),
 				DeleteOnTermination:      aws.Bool(true),
-				SubnetId:                 aws.String(s.Conf.Subnet),
-				Groups:                   aws.StringSlice([]string{s.Conf.SecurityGroup}),
+				SubnetId:                 aws.String(rc.Subnet),
+				Groups:                   aws.StringSlice([]string{rc.SecurityGroup}),
 			},
 		},
 		IamInstanceProfile: &ec2.IamInstanceProfileSpecification{
@@ -194,16 +368,16 @@ func (s *service) RunDeployment(ctx context.Context, deployment models.Deploymen
 	}
 
 	if deployment.SpotInstance {
-		instanceId, err := s.runSpotInstance(ctx, encodedConfig, false)
+		instanceId, err := s.runSpotInstance(ctx, deployment, encodedConfig, false)
 		return instanceId, err
 	}
 
-	instanceId, err := s.runInstance(ctx, encodedConfig, false)
+	instanceId, err := s.runInstance(ctx, deployment, encodedConfig, false)
 	return instanceId, err
 }
 
-func (s *service) stopInstance(ctx context.Context, InstanceId string) error {
-	ec2Session := ec2.New(s.session)
+func (s *service) stopInstance(ctx context.Context, region string, InstanceId string) error {
+	ec2Session := ec2.New(s.session(region))
 
 	cfg := ec2.TerminateInstancesInput{
 		InstanceIds: aws.StringSlice([]string{InstanceId}),
@@ -214,8 +388,8 @@ func (s *service) stopInstance(ctx context.Context, InstanceId string) error {
 	return err
 }
 
-func (s *service) stopSpotInstance(ctx context.Context, InstanceId string) error {
-	ec2Session := ec2.New(s.session)
+func (s *service) stopSpotInstance(ctx context.Context, region string, InstanceId string) error {
+	ec2Session := ec2.New(s.session(region))
 
 	input := &ec2.CancelSpotInstanceRequestsInput{
 		SpotInstanceRequestIds: aws.StringSlice([]string{InstanceId}),
@@ -256,11 +430,12 @@ func (s *service) stopSpotInstance(ctx context.Context, InstanceId string) error
 
 func (s *service) StopDeployment(ctx context.Context, deployment models.Deployment) error {
 	InstanceId := deployment.InstanceID
+	region := s.region(deployment)
 
 	if deployment.SpotInstance {
-		return s.stopSpotInstance(ctx, InstanceId)
+		return s.stopSpotInstance(ctx, region, InstanceId)
 	}
-	return s.stopInstance(ctx, InstanceId)
+	return s.stopInstance(ctx, region, InstanceId)
 }
 
 func (s *service) GetDepDetail(id int) (string, error) {
@@ -272,7 +447,8 @@ func (s *service) GetServiceConfig() ServiceConfig {
 }
 
 func (s *service) GetDeploymentStream(ctx context.Context, deployment models.Deployment) (*cloudwatchlogs.LogStream, error) {
-	cwLogs := cloudwatchlogs.New(s.session)
+	// deployments log to the home region wherever they run
+	cwLogs := cloudwatchlogs.New(s.session(s.Conf.HomeRegion()))
 
 	searchParams := &cloudwatchlogs.DescribeLogStreamsInput{
 		LogGroupName:        aws.String(s.Conf.LogGroup), // Required
@@ -307,7 +483,41 @@ func isNotFound(err error) bool {
 	return false
 }
 
+// byRegion groups deployments by region.
+func (s *service) byRegion(deployments []models.Deployment) map[string][]models.Deployment {
+	regions := make(map[string][]models.Deployment)
+	for _, deployment := range deployments {
+		region := s.region(deployment)
+		regions[region] = append(regions[region], deployment)
+	}
+	return regions
+}
+
+// describeRegions calls describe with the deployments of each region, and
+// merges the results.
+func (s *service) describeRegions(ctx context.Context, deployments []models.Deployment, describe func(context.Context, string, []models.Deployment) (map[string]string, error)) (map[string]string, error) {
+	ret := make(map[string]string)
+	for region, deps := range s.byRegion(deployments) {
+		results, err := describe(ctx, region, deps)
+		for id, v := range results {
+			ret[id] = v
+		}
+		if err != nil {
+			return ret, err
+		}
+	}
+	return ret, nil
+}
+
 func (s *service) DescribeInstanceStatus(ctx context.Context, deployments []models.Deployment) (map[string]string, error) {
+	return s.describeRegions(ctx, deployments, s.describeInstanceStatus)
+}
+
+func (s *service) DescribeInstanceIPs(ctx context.Context, deployments []models.Deployment) (map[string]string, error) {
+	return s.describeRegions(ctx, deployments, s.describeInstanceIPs)
+}
+
+func (s *service) describeInstanceStatus(ctx context.Context, region string, deployments []models.Deployment) (map[string]string, error) {
 	ret := make(map[string]string)
 
 	var instanceids []string
@@ -320,7 +530,7 @@ func (s *service) DescribeInstanceStatus(ctx context.Context, deployments []mode
 		}
 
 	}
-	ec2Session := ec2.New(s.session)
+	ec2Session := ec2.New(s.session(region))
 
 	if len(instanceids) > 0 {
 		// regular instances
@@ -390,7 +600,7 @@ func (s *service) DescribeInstanceStatus(ctx context.Context, deployments []mode
 	return ret, nil
 }
 
-func (s *service) DescribeInstanceIPs(ctx context.Context, deployments []models.Deployment) (map[string]string, error) {
+func (s *service) describeInstanceIPs(ctx context.Context, region string, deployments []models.Deployment) (map[string]string, error) {
 	ret := make(map[string]string)
 
 	var instanceids []string
@@ -403,7 +613,7 @@ func (s *service) DescribeInstanceIPs(ctx context.Context, deployments []models.
 		}
 
 	}
-	ec2Session := ec2.New(s.session)
+	ec2Session := ec2.New(s.session(region))
 
 	if len(instanceids) > 0 {
 		// regular instances
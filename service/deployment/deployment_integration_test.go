@@ -37,7 +37,7 @@ func TestDeploySpotInstance(t *testing.T) {
 	}
 
 	d := newService(c)
-	_, err = d.runSpotInstance(context.Background(), ENCODED_CONFIG, true)
+	_, err = d.runSpotInstance(context.Background(), models.Deployment{}, ENCODED_CONFIG, true)
 	if !DryRunOk(err) {
 		t.Error(err)
 	}
@@ -51,7 +51,7 @@ func TestDeployInstance(t *testing.T) {
 	}
 
 	d := newService(c)
-	_, err = d.runInstance(context.Background(), ENCODED_CONFIG, true)
+	_, err = d.runInstance(context.Background(), models.Deployment{}, ENCODED_CONFIG, true)
 	if !DryRunOk(err) {
 		t.Error(err)
 	}
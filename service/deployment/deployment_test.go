@@ -87,3 +87,77 @@ func TestRoundTripEncode(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestPlacement(t *testing.T) {
+	conf := ServiceConfig{
+		Region:              "us-east-1",
+		Regions:             []string{"us-east-1", "eu-west-1", "us-west-2"},
+		InstanceTypes:       []string{"f1.2xlarge", "f1.16xlarge"},
+		DefaultInstanceType: "f1.2xlarge",
+		RegionAMIs:          []string{"eu-west-1=ami-eu", "us-west-2=ami-us"},
+		RegionSubnets:       []string{"eu-west-1=subnet-eu", "us-west-2=subnet-us"},
+		// us-west-2 has no security group
+		RegionSecurityGroups: []string{"eu-west-1=sg-eu"},
+	}
+
+	cases := []struct {
+		region, instanceType string
+		expectedRegion       string
+		expectedType         string
+		err                  error
+	}{
+		{"", "", "us-east-1", "f1.2xlarge", nil},
+		{"eu-west-1", "f1.16xlarge", "eu-west-1", "f1.16xlarge", nil},
+		{"ap-southeast-2", "", "", "", ErrUnknownRegion},
+		{"us-west-2", "", "", "", ErrUnknownRegion},
+		{"", "f1.4xlarge", "", "", ErrUnknownInstanceType},
+	}
+	for _, c := range cases {
+		region, instanceType, err := conf.Placement(c.region, c.instanceType)
+		if region != c.expectedRegion || instanceType != c.expectedType || err != c.err {
+			t.Errorf("Placement(%q, %q) = %q, %q, %v", c.region, c.instanceType, region, instanceType, err)
+		}
+	}
+}
+
+func TestRegionConfig(t *testing.T) {
+	conf := ServiceConfig{
+		Region:        "us-east-1",
+		AMI:           "ami-home",
+		Subnet:        "subnet-home",
+		SecurityGroup: "sg-home",
+		RegionAMIs:    []string{"eu-west-1=ami-eu"},
+		RegionSubnets: []string{"eu-west-1=subnet-eu"},
+		SpotZones:     []string{"us-east-1=us-east-1d"},
+	}
+
+	expected := RegionConfig{AMI: "ami-home", Subnet: "subnet-home", SecurityGroup: "sg-home", SpotZone: "us-east-1d"}
+	if rc := conf.RegionConfig("us-east-1"); rc != expected {
+		t.Errorf("Expected %+v, got %+v", expected, rc)
+	}
+
+	expected = RegionConfig{AMI: "ami-eu", Subnet: "subnet-eu"}
+	if rc := conf.RegionConfig("eu-west-1"); rc != expected {
+		t.Errorf("Expected %+v, got %+v", expected, rc)
+	}
+}
+
+func TestValidateRegions(t *testing.T) {
+	conf := ServiceConfig{
+		Region:        "us-east-1",
+		AMI:           "ami-home",
+		Subnet:        "subnet-home",
+		SecurityGroup: "sg-home",
+		Regions:       []string{"us-east-1", "eu-west-1"},
+		RegionAMIs:    []string{"eu-west-1=ami-eu"},
+		RegionSubnets: []string{"eu-west-1=subnet-eu"},
+	}
+	if err := conf.ValidateRegions(); err == nil {
+		t.Error("Expected eu-west-1 without a security group to be invalid")
+	}
+
+	conf.RegionSecurityGroups = []string{"eu-west-1=sg-eu"}
+	if err := conf.ValidateRegions(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,261 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ErrNoLogStream is returned by GetDeploymentStream for deployments which
+// don't log to CloudWatch. Their logs are read with DeploymentLogs.
+var ErrNoLogStream = errors.New("deployment does not log to cloudwatch")
+
+// LogReader is implemented by services whose deployments log somewhere
+// other than CloudWatch.
+type LogReader interface {
+	// DeploymentLogs follows the logs of a deployment until it finishes,
+	// or ctx is done. It returns ErrNoLogStream for deployments whose
+	// logs are in CloudWatch.
+	DeploymentLogs(ctx context.Context, deployment models.Deployment) (io.ReadCloser, error)
+}
+
+// DockerClient is the part of the docker client used for deployments.
+type DockerClient interface {
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+	ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error)
+	ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error
+	ContainerStop(ctx context.Context, containerID string, timeout *time.Duration) error
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	ContainerLogs(ctx context.Context, container string, options types.ContainerLogsOptions) (io.ReadCloser, error)
+}
+
+// dockerStopTimeout is how long a deployment has to exit after being
+// asked to stop, before it's killed.
+const dockerStopTimeout = 30 * time.Second
+
+// dockerService runs deployments as containers on a docker daemon. Its
+// instance IDs are container IDs. Stopped containers are kept, so their
+// logs can still be read, and are labelled responsible=reco-deployment.
+type dockerService struct {
+	client DockerClient
+	Conf   ServiceConfig
+}
+
+// NewDocker returns a service running deployments on the docker daemon
+// client talks to.
+func NewDocker(conf ServiceConfig, client DockerClient) Service {
+	return &dockerService{client: client, Conf: conf}
+}
+
+// dockerEnv passes the deployment's configuration to its container, which
+// on EC2 comes from the instance's user data. As with fake-batch, storage
+// credentials are passed through from our own environment.
+func dockerEnv(conf Deployment) ([]string, error) {
+	encoded, err := conf.String()
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		"DEPLOYMENT_CONFIG=" + encoded,
+		"CALLBACK_URL=" + conf.CallbackUrl,
+		"ARTIFACT_URL=" + conf.Build.ArtifactUrl,
+		"AGFI=" + conf.Build.Agfi,
+		"AWS_ACCESS_KEY_ID=" + os.Getenv("AWS_ACCESS_KEY_ID"),
+		"AWS_SECRET_ACCESS_KEY=" + os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		"S3_ENDPOINT=" + os.Getenv("S3_ENDPOINT"),
+	}, nil
+}
+
+// pullImage pulls a deployment's image, unless the daemon already has it.
+func (s *dockerService) pullImage(ctx context.Context, image string) error {
+	_, _, err := s.client.ImageInspectWithRaw(ctx, image)
+	if err == nil || !client.IsErrNotFound(err) {
+		return err
+	}
+
+	pull, err := s.client.ImagePull(ctx, image, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	// the pull only finishes once its progress has been read
+	_, err = io.Copy(ioutil.Discard, pull)
+	pull.Close()
+	return err
+}
+
+func (s *dockerService) RunDeployment(ctx context.Context, deployment models.Deployment, callbackUrl string) (string, error) {
+	conf := s.Conf.ContainerConfig(deployment, callbackUrl)
+	env, err := dockerEnv(conf)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.pullImage(ctx, conf.Container.Image)
+	if err != nil {
+		return "", err
+	}
+
+	created, err := s.client.ContainerCreate(ctx,
+		&container.Config{
+			Image: conf.Container.Image,
+			Cmd:   []string{"/bin/sh", "-c", conf.Container.Command},
+			Env:   env,
+			Labels: map[string]string{
+				"responsible": "reco-deployment",
+				"deployment":  deployment.ID,
+			},
+		},
+		&container.HostConfig{
+			NetworkMode: container.NetworkMode(s.Conf.DockerNetwork),
+		},
+		nil,
+		conf.Logs.Prefix,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.client.ContainerStart(ctx, created.ID, types.ContainerStartOptions{})
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (s *dockerService) StopDeployment(ctx context.Context, deployment models.Deployment) error {
+	timeout := dockerStopTimeout
+	err := s.client.ContainerStop(ctx, deployment.InstanceID, &timeout)
+	if client.IsErrNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *dockerService) GetDepDetail(id int) (string, error) {
+	return "imaginary", nil
+}
+
+func (s *dockerService) GetServiceConfig() ServiceConfig {
+	return s.Conf
+}
+
+func (s *dockerService) GetDeploymentStream(ctx context.Context, deployment models.Deployment) (*cloudwatchlogs.LogStream, error) {
+	return nil, ErrNoLogStream
+}
+
+// DeploymentLogs follows the container's stdout and stderr.
+func (s *dockerService) DeploymentLogs(ctx context.Context, deployment models.Deployment) (io.ReadCloser, error) {
+	raw, err := s.client.ContainerLogs(ctx, deployment.InstanceID, types.ContainerLogsOptions{
+		Follow:     true,
+		ShowStderr: true,
+		ShowStdout: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// docker multiplexes stdout and stderr, so strip its framing
+	r, w := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(w, w, raw)
+		raw.Close()
+		w.CloseWithError(err)
+	}()
+	return r, nil
+}
+
+// dockerInstanceState maps a container's state onto the EC2 instance
+// state it is closest to, which is what Instances understands.
+func dockerInstanceState(state *types.ContainerState) string {
+	switch state.Status {
+	case "created":
+		return ec2.InstanceStateNamePending
+	case "running", "restarting", "paused":
+		return ec2.InstanceStateNameRunning
+	case "removing":
+		return ec2.InstanceStateNameShuttingDown
+	default:
+		return ec2.InstanceStateNameTerminated
+	}
+}
+
+// inspect calls f with each deployment's container, skipping ones which
+// no longer exist.
+func (s *dockerService) inspect(ctx context.Context, deployments []models.Deployment, f func(string, types.ContainerJSON)) error {
+	for _, deployment := range deployments {
+		if deployment.InstanceID == "" {
+			continue
+		}
+		info, err := s.client.ContainerInspect(ctx, deployment.InstanceID)
+		if client.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		f(deployment.InstanceID, info)
+	}
+	return nil
+}
+
+func (s *dockerService) DescribeInstanceStatus(ctx context.Context, deployments []models.Deployment) (map[string]string, error) {
+	ret := make(map[string]string)
+	err := s.inspect(ctx, deployments, func(id string, info types.ContainerJSON) {
+		if info.ContainerJSONBase != nil && info.State != nil {
+			ret[id] = dockerInstanceState(info.State)
+		}
+	})
+	return ret, err
+}
+
+func (s *dockerService) DescribeInstanceIPs(ctx context.Context, deployments []models.Deployment) (map[string]string, error) {
+	ret := make(map[string]string)
+	err := s.inspect(ctx, deployments, func(id string, info types.ContainerJSON) {
+		if info.NetworkSettings == nil {
+			return
+		}
+		if ip := info.NetworkSettings.IPAddress; ip != "" {
+			ret[id] = ip
+			return
+		}
+		for _, endpoint := range info.NetworkSettings.Networks {
+			if endpoint != nil && endpoint.IPAddress != "" {
+				ret[id] = endpoint.IPAddress
+				return
+			}
+		}
+	})
+	return ret, err
+}
+
+// NewDockerHost returns a service for the docker daemon at host, such as
+// tcp://fpga1:2376, or the local daemon if host is empty.
+func NewDockerHost(conf ServiceConfig, host string) (Service, error) {
+	var (
+		c   *client.Client
+		err error
+	)
+	if host == "" {
+		c, err = client.NewEnvClient()
+	} else {
+		c, err = client.NewClient(host, "", nil, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure docker client for %q: %v", host, err)
+	}
+	return NewDocker(conf, c), nil
+}
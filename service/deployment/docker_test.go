@@ -0,0 +1,178 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+var errFakeNotFound = errors.New("not found")
+
+// fakeImageNotFound is the docker client's error for missing images.
+type fakeImageNotFound struct{}
+
+func (fakeImageNotFound) Error() string  { return "no such image" }
+func (fakeImageNotFound) NotFound() bool { return true }
+
+// fakeDocker is a docker daemon of containers in memory.
+type fakeDocker struct {
+	created    *container.Config
+	host       *container.HostConfig
+	containers map[string]types.ContainerJSON
+	logs       []byte
+	images     map[string]bool
+	pulls      []string
+}
+
+func (f *fakeDocker) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	if !f.images[imageID] {
+		return types.ImageInspect{}, nil, fakeImageNotFound{}
+	}
+	return types.ImageInspect{ID: imageID}, nil, nil
+}
+
+func (f *fakeDocker) ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	f.pulls = append(f.pulls, refStr)
+	return ioutil.NopCloser(strings.NewReader("{}")), nil
+}
+
+func (f *fakeDocker) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error) {
+	f.created = config
+	f.host = hostConfig
+	f.containers["c1"] = types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			State: &types.ContainerState{Status: "created"},
+		},
+	}
+	return container.ContainerCreateCreatedBody{ID: "c1"}, nil
+}
+
+func (f *fakeDocker) ContainerStart(ctx context.Context, containerID string, options types.ContainerStartOptions) error {
+	f.containers[containerID].State.Status = "running"
+	return nil
+}
+
+func (f *fakeDocker) ContainerStop(ctx context.Context, containerID string, timeout *time.Duration) error {
+	c, ok := f.containers[containerID]
+	if !ok {
+		return errFakeNotFound
+	}
+	c.State.Status = "exited"
+	return nil
+}
+
+func (f *fakeDocker) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	c, ok := f.containers[containerID]
+	if !ok {
+		return c, errFakeNotFound
+	}
+	return c, nil
+}
+
+func (f *fakeDocker) ContainerLogs(ctx context.Context, container string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(f.logs)), nil
+}
+
+func TestDockerDeployment(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeDocker{containers: make(map[string]types.ContainerJSON)}
+	s := NewDocker(ServiceConfig{Image: "runtime:latest", DockerNetwork: "platform"}, client)
+
+	dep := models.Deployment{ID: "d1", Command: "echo hello"}
+	id, err := s.RunDeployment(ctx, dep, "http://local/deployments/d1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "c1" {
+		t.Errorf("Expected container c1, got %s", id)
+	}
+	if client.created.Image != "runtime:latest" || client.created.Labels["deployment"] != "d1" {
+		t.Errorf("Unexpected container %+v", client.created)
+	}
+	if !reflect.DeepEqual([]string(client.created.Cmd), []string{"/bin/sh", "-c", "echo hello"}) {
+		t.Errorf("Unexpected command %v", client.created.Cmd)
+	}
+	if client.host.NetworkMode != "platform" {
+		t.Errorf("Expected network platform, got %s", client.host.NetworkMode)
+	}
+
+	dep.InstanceID = id
+	statuses, err := s.DescribeInstanceStatus(ctx, []models.Deployment{dep})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses["c1"] != ec2.InstanceStateNameRunning {
+		t.Errorf("Expected c1 to be running, got %v", statuses)
+	}
+
+	if err := s.StopDeployment(ctx, dep); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err = s.DescribeInstanceStatus(ctx, []models.Deployment{dep})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses["c1"] != ec2.InstanceStateNameTerminated {
+		t.Errorf("Expected c1 to be terminated, got %v", statuses)
+	}
+}
+
+func TestDockerDeploymentPull(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeDocker{
+		containers: make(map[string]types.ContainerJSON),
+		images:     map[string]bool{"runtime:latest": true},
+	}
+	s := NewDocker(ServiceConfig{Image: "runtime:latest"}, client)
+
+	_, err := s.RunDeployment(ctx, models.Deployment{ID: "d1"}, "http://local/deployments/d1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.pulls) != 0 {
+		t.Errorf("Expected the local image to be used, got pulls %v", client.pulls)
+	}
+
+	client.images = nil
+	_, err = s.RunDeployment(ctx, models.Deployment{ID: "d2"}, "http://local/deployments/d2/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(client.pulls, []string{"runtime:latest"}) {
+		t.Errorf("Expected the image to be pulled, got %v", client.pulls)
+	}
+}
+
+func TestDockerDeploymentLogs(t *testing.T) {
+	var framed bytes.Buffer
+	stdcopy.NewStdWriter(&framed, stdcopy.Stdout).Write([]byte("hello\n"))
+	stdcopy.NewStdWriter(&framed, stdcopy.Stderr).Write([]byte("world\n"))
+
+	s := NewDocker(ServiceConfig{}, &fakeDocker{logs: framed.Bytes()})
+	logs, err := s.(LogReader).DeploymentLogs(context.Background(), models.Deployment{InstanceID: "c1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logs.Close()
+
+	out, err := ioutil.ReadAll(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hello\nworld\n" {
+		t.Errorf("Unexpected logs %q", out)
+	}
+}
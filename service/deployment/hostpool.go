@@ -0,0 +1,170 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// ErrNoHosts is returned when a host pool has no hosts to deploy to.
+var ErrNoHosts = errors.New("host pool has no hosts")
+
+// HostPool is a Service spreading deployments over a pool of FPGA hosts,
+// each with its own Service. Instance IDs are those of the host's service
+// prefixed with "<host>/", so the pool knows which host owns each one.
+type HostPool struct {
+	hosts map[string]Service
+	names []string
+	conf  ServiceConfig
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewHostPool returns an empty host pool.
+func NewHostPool(conf ServiceConfig) *HostPool {
+	return &HostPool{
+		hosts: make(map[string]Service),
+		conf:  conf,
+	}
+}
+
+// AddHost adds a host to the pool.
+func (p *HostPool) AddHost(name string, s Service) {
+	if _, ok := p.hosts[name]; !ok {
+		p.names = append(p.names, name)
+		sort.Strings(p.names)
+	}
+	p.hosts[name] = s
+}
+
+// pick returns the next host in turn.
+func (p *HostPool) pick() (string, Service, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.names) == 0 {
+		return "", nil, ErrNoHosts
+	}
+	name := p.names[p.next%len(p.names)]
+	p.next++
+	return name, p.hosts[name], nil
+}
+
+// host returns the host owning a deployment, and the deployment as the
+// host knows it.
+func (p *HostPool) host(deployment models.Deployment) (Service, models.Deployment, error) {
+	parts := strings.SplitN(deployment.InstanceID, "/", 2)
+	if len(parts) != 2 {
+		return nil, deployment, ErrUnknownBackend
+	}
+	s, ok := p.hosts[parts[0]]
+	if !ok {
+		return nil, deployment, ErrUnknownBackend
+	}
+	deployment.InstanceID = parts[1]
+	return s, deployment, nil
+}
+
+// RunDeployment runs the deployment on the next host.
+func (p *HostPool) RunDeployment(ctx context.Context, deployment models.Deployment, callbackUrl string) (string, error) {
+	name, s, err := p.pick()
+	if err != nil {
+		return "", err
+	}
+	id, err := s.RunDeployment(ctx, deployment, callbackUrl)
+	if err != nil {
+		return "", err
+	}
+	return name + "/" + id, nil
+}
+
+// StopDeployment stops the deployment on its host.
+func (p *HostPool) StopDeployment(ctx context.Context, deployment models.Deployment) error {
+	s, dep, err := p.host(deployment)
+	if err != nil {
+		return err
+	}
+	return s.StopDeployment(ctx, dep)
+}
+
+// GetDepDetail does nothing at the moment
+func (p *HostPool) GetDepDetail(id int) (string, error) {
+	return "imaginary", nil
+}
+
+// GetDeploymentStream gets the log stream of the deployment from its host.
+func (p *HostPool) GetDeploymentStream(ctx context.Context, deployment models.Deployment) (*cloudwatchlogs.LogStream, error) {
+	s, dep, err := p.host(deployment)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDeploymentStream(ctx, dep)
+}
+
+// DeploymentLogs follows the logs of the deployment on its host.
+func (p *HostPool) DeploymentLogs(ctx context.Context, deployment models.Deployment) (io.ReadCloser, error) {
+	s, dep, err := p.host(deployment)
+	if err != nil {
+		return nil, err
+	}
+	if lr, ok := s.(LogReader); ok {
+		return lr.DeploymentLogs(ctx, dep)
+	}
+	return nil, ErrNoLogStream
+}
+
+// DescribeInstanceStatus asks each host about its deployments.
+func (p *HostPool) DescribeInstanceStatus(ctx context.Context, deployments []models.Deployment) (map[string]string, error) {
+	return p.fanOut(deployments, func(s Service, deps []models.Deployment) (map[string]string, error) {
+		return s.DescribeInstanceStatus(ctx, deps)
+	})
+}
+
+// DescribeInstanceIPs asks each host about its deployments.
+func (p *HostPool) DescribeInstanceIPs(ctx context.Context, deployments []models.Deployment) (map[string]string, error) {
+	return p.fanOut(deployments, func(s Service, deps []models.Deployment) (map[string]string, error) {
+		return s.DescribeInstanceIPs(ctx, deps)
+	})
+}
+
+// GetServiceConfig outputs the configuration of the service
+func (p *HostPool) GetServiceConfig() ServiceConfig {
+	return p.conf
+}
+
+// fanOut groups deployments by host, and merges the results of calling
+// describe on each, keyed by the pool's instance IDs. Deployments on hosts
+// no longer in the pool are left out.
+func (p *HostPool) fanOut(deployments []models.Deployment, describe func(Service, []models.Deployment) (map[string]string, error)) (map[string]string, error) {
+	byHost := make(map[string][]models.Deployment)
+	for _, deployment := range deployments {
+		parts := strings.SplitN(deployment.InstanceID, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		deployment.InstanceID = parts[1]
+		byHost[parts[0]] = append(byHost[parts[0]], deployment)
+	}
+
+	ret := make(map[string]string)
+	for name, deps := range byHost {
+		s, ok := p.hosts[name]
+		if !ok {
+			continue
+		}
+		results, err := describe(s, deps)
+		if err != nil {
+			return ret, err
+		}
+		for id, v := range results {
+			ret[name+"/"+id] = v
+		}
+	}
+	return ret, nil
+}
@@ -2,6 +2,7 @@ package deployment
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/ReconfigureIO/platform/models"
@@ -29,6 +30,8 @@ type Instances interface {
 	UpdateInstanceStatus(context.Context) error
 	AddDeploymentEvent(context.Context, models.Deployment, models.DeploymentEvent) error
 	FindIPs(context.Context) error
+	StartScheduled(ctx context.Context, now time.Time, start ScheduledStart) error
+	StopExpired(ctx context.Context, now time.Time) error
 }
 
 type instances struct {
@@ -171,3 +174,145 @@ func (instances *instances) FindIPs(ctx context.Context) error {
 
 	return nil
 }
+
+// ScheduledStart is how StartScheduled starts deployments.
+type ScheduledStart struct {
+	// CallbackURL gives where each deployment should send its events.
+	CallbackURL func(models.Deployment) string
+	// HasHours returns if a deployment's user has the hours left to run
+	// it.
+	HasHours func(models.Deployment) (bool, error)
+	// Push, if set, pushes deployments onto the deployment queue, which
+	// starts them, instead of them being started straight away.
+	Push func(models.Deployment) error
+}
+
+// StartScheduled starts the deployments scheduled to start by now, or
+// queues them to be started.
+func (instances *instances) StartScheduled(ctx context.Context, now time.Time, start ScheduledStart) error {
+	scheduled, err := instances.Deployments.GetScheduled(now)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"count": len(scheduled),
+	}).Info("Starting scheduled deployments")
+
+	for _, deployment := range scheduled {
+		queued := models.DeploymentEvent{
+			Timestamp: time.Now(),
+			Status:    models.StatusQueued,
+			Message:   fmt.Sprintf("Scheduled start at %s", deployment.StartAt.UTC().Format(time.RFC3339)),
+		}
+
+		// the queue checks the user's hours when it runs the deployment
+		if start.Push != nil {
+			err = instances.Deployments.AddEvent(deployment, queued)
+			if err != nil {
+				return err
+			}
+			err = start.Push(deployment)
+			if err != nil {
+				err = instances.scheduledStartFailed(deployment, err, "Scheduled start failed")
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		hasHours, err := start.HasHours(deployment)
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{
+				"deployment": deployment.ID,
+			}).Error("Couldn't check hours of scheduled deployment")
+			continue
+		}
+		if !hasHours {
+			err = instances.scheduledStartFailed(deployment, nil, "Scheduled start failed, no deployment hours left")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		instanceID, err := instances.Deploy.RunDeployment(ctx, deployment, start.CallbackURL(deployment))
+		if err != nil {
+			err = instances.scheduledStartFailed(deployment, err, "Scheduled start failed")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = instances.Deployments.SetInstance(deployment, instanceID)
+		if err != nil {
+			// an untracked instance would be started again on the next
+			// run, so it's stopped
+			deployment.InstanceID = instanceID
+			stopErr := instances.Deploy.StopDeployment(ctx, deployment)
+			if stopErr != nil {
+				log.WithError(stopErr).WithFields(log.Fields{
+					"deployment": deployment.ID,
+					"instance":   instanceID,
+				}).Error("Couldn't stop untracked instance of scheduled deployment")
+			}
+			err = instances.scheduledStartFailed(deployment, err, "Scheduled start failed")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = instances.Deployments.AddEvent(deployment, queued)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduledStartFailed marks a scheduled deployment which couldn't be
+// started, because of cause if it isn't nil, as errored.
+func (instances *instances) scheduledStartFailed(deployment models.Deployment, cause error, message string) error {
+	entry := log.WithFields(log.Fields{"deployment": deployment.ID})
+	if cause != nil {
+		entry = entry.WithError(cause)
+	}
+	entry.Error("Couldn't start scheduled deployment")
+
+	event := models.DeploymentEvent{
+		Timestamp: time.Now(),
+		Status:    models.StatusErrored,
+		Message:   message,
+		Code:      1,
+	}
+	return instances.Deployments.AddEvent(deployment, event)
+}
+
+// StopExpired stops the deployments which have been running for longer than
+// their maximum duration at now.
+func (instances *instances) StopExpired(ctx context.Context, now time.Time) error {
+	expired, err := instances.Deployments.GetExpired(now)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"count": len(expired),
+	}).Info("Stopping expired deployments")
+
+	for _, deployment := range expired {
+		event := models.DeploymentEvent{
+			Timestamp: time.Now(),
+			Status:    models.StatusTerminating,
+			Message:   fmt.Sprintf("Reached maximum duration of %s", time.Duration(deployment.MaxDuration)*time.Second),
+		}
+		err = instances.AddDeploymentEvent(ctx, deployment, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package deployment
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/ReconfigureIO/platform/models"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -142,3 +144,182 @@ func TestUpdateInstanceShouldNotTerminateQueued(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestStartScheduled(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	deployments := []models.Deployment{
+		models.Deployment{ID: "dep", StartAt: &now},
+	}
+
+	deploymentRepo := models.NewMockDeploymentRepo(mockCtrl)
+	deploymentService := NewMockService(mockCtrl)
+
+	deploymentRepo.EXPECT().GetScheduled(now).Return(deployments, nil)
+	deploymentService.EXPECT().RunDeployment(ctx, deployments[0], "callback/dep").Return("foo", nil)
+	deploymentRepo.EXPECT().SetInstance(deployments[0], "foo").Return(nil)
+	deploymentRepo.EXPECT().AddEvent(deployments[0], gomock.Any()).Do(func(_ models.Deployment, event models.DeploymentEvent) {
+		if event.Status != models.StatusQueued {
+			t.Errorf("Expected a QUEUED event, got %s", event.Status)
+		}
+	}).Return(nil)
+
+	start := ScheduledStart{
+		CallbackURL: func(dep models.Deployment) string {
+			return "callback/" + dep.ID
+		},
+		HasHours: func(models.Deployment) (bool, error) {
+			return true, nil
+		},
+	}
+	err := NewInstances(deploymentRepo, deploymentService).StartScheduled(ctx, now, start)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStartScheduledWithoutHours(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	deployments := []models.Deployment{
+		models.Deployment{ID: "dep", StartAt: &now},
+	}
+
+	// no instance is started
+	deploymentRepo := models.NewMockDeploymentRepo(mockCtrl)
+	deploymentService := NewMockService(mockCtrl)
+
+	deploymentRepo.EXPECT().GetScheduled(now).Return(deployments, nil)
+	deploymentRepo.EXPECT().AddEvent(deployments[0], gomock.Any()).Do(func(_ models.Deployment, event models.DeploymentEvent) {
+		if event.Status != models.StatusErrored {
+			t.Errorf("Expected an ERRORED event, got %s", event.Status)
+		}
+	}).Return(nil)
+
+	start := ScheduledStart{
+		CallbackURL: func(dep models.Deployment) string {
+			return "callback/" + dep.ID
+		},
+		HasHours: func(models.Deployment) (bool, error) {
+			return false, nil
+		},
+	}
+	err := NewInstances(deploymentRepo, deploymentService).StartScheduled(ctx, now, start)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStartScheduledStopsUntrackedInstance(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	deployments := []models.Deployment{
+		models.Deployment{ID: "dep", StartAt: &now},
+	}
+	started := deployments[0]
+	started.InstanceID = "foo"
+
+	deploymentRepo := models.NewMockDeploymentRepo(mockCtrl)
+	deploymentService := NewMockService(mockCtrl)
+
+	deploymentRepo.EXPECT().GetScheduled(now).Return(deployments, nil)
+	deploymentService.EXPECT().RunDeployment(ctx, deployments[0], "callback/dep").Return("foo", nil)
+	deploymentRepo.EXPECT().SetInstance(deployments[0], "foo").Return(errors.New("database gone"))
+	deploymentService.EXPECT().StopDeployment(ctx, started).Return(nil)
+	deploymentRepo.EXPECT().AddEvent(started, gomock.Any()).Do(func(_ models.Deployment, event models.DeploymentEvent) {
+		if event.Status != models.StatusErrored {
+			t.Errorf("Expected an ERRORED event, got %s", event.Status)
+		}
+	}).Return(nil)
+
+	start := ScheduledStart{
+		CallbackURL: func(dep models.Deployment) string {
+			return "callback/" + dep.ID
+		},
+		HasHours: func(models.Deployment) (bool, error) {
+			return true, nil
+		},
+	}
+	err := NewInstances(deploymentRepo, deploymentService).StartScheduled(ctx, now, start)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStartScheduledQueued(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	deployments := []models.Deployment{
+		models.Deployment{ID: "dep", StartAt: &now},
+	}
+
+	// the queue starts the deployment
+	deploymentRepo := models.NewMockDeploymentRepo(mockCtrl)
+	deploymentService := NewMockService(mockCtrl)
+
+	deploymentRepo.EXPECT().GetScheduled(now).Return(deployments, nil)
+	deploymentRepo.EXPECT().AddEvent(deployments[0], gomock.Any()).Do(func(_ models.Deployment, event models.DeploymentEvent) {
+		if event.Status != models.StatusQueued {
+			t.Errorf("Expected a QUEUED event, got %s", event.Status)
+		}
+	}).Return(nil)
+
+	var pushed []string
+	start := ScheduledStart{
+		Push: func(dep models.Deployment) error {
+			pushed = append(pushed, dep.ID)
+			return nil
+		},
+	}
+	err := NewInstances(deploymentRepo, deploymentService).StartScheduled(ctx, now, start)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(pushed) != 1 || pushed[0] != "dep" {
+		t.Errorf("Expected dep to be pushed, got %v", pushed)
+	}
+}
+
+func TestStopExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	deployments := []models.Deployment{
+		models.Deployment{InstanceID: "foo", MaxDuration: 3600},
+	}
+
+	deploymentRepo := models.NewMockDeploymentRepo(mockCtrl)
+	deploymentService := NewMockService(mockCtrl)
+
+	deploymentRepo.EXPECT().GetExpired(now).Return(deployments, nil)
+	deploymentRepo.EXPECT().AddEvent(deployments[0], gomock.Any()).Do(func(_ models.Deployment, event models.DeploymentEvent) {
+		if event.Status != models.StatusTerminating || event.Message != "Reached maximum duration of 1h0m0s" {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	}).Return(nil)
+	deploymentService.EXPECT().StopDeployment(ctx, deployments[0]).Return(nil)
+
+	err := NewInstances(deploymentRepo, deploymentService).StopExpired(ctx, now)
+	if err != nil {
+		t.Error(err)
+	}
+}
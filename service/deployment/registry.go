@@ -0,0 +1,249 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+const (
+	// BackendEC2 runs deployments on EC2 F1 instances.
+	BackendEC2 = "ec2-f1"
+	// BackendDocker runs deployments as local Docker containers.
+	BackendDocker = "docker"
+	// BackendHostPool runs deployments on a pool of on-prem FPGA hosts.
+	BackendHostPool = "host-pool"
+)
+
+// ErrUnknownBackend is returned for deployments targeting a backend which
+// isn't registered.
+var ErrUnknownBackend = errors.New("unknown deployment backend")
+
+// Selector is implemented by services which can run deployments on more
+// than one backend.
+type Selector interface {
+	// Backends returns the names of the available backends.
+	Backends() []string
+	// DefaultBackend returns the backend used when none is requested.
+	DefaultBackend() string
+}
+
+// Resolve returns the backend s will run a deployment on, given the
+// backends requested in order of preference, e.g. the deployment's then
+// the project's. Empty requests are ignored.
+func Resolve(s Service, requested ...string) (string, error) {
+	backends, def := []string{BackendEC2}, BackendEC2
+	if selector, ok := s.(Selector); ok {
+		backends, def = selector.Backends(), selector.DefaultBackend()
+	}
+
+	for _, backend := range requested {
+		if backend == "" {
+			continue
+		}
+		if !inSlice(backends, backend) {
+			return "", ErrUnknownBackend
+		}
+		return backend, nil
+	}
+	return def, nil
+}
+
+// Registry is a Service which passes each deployment to the backend
+// recorded on it, so any number of backends can be used side by side.
+type Registry struct {
+	backends map[string]Service
+	def      string
+	conf     ServiceConfig
+}
+
+// NewRegistry returns a registry running deployments which don't name a
+// backend on def.
+func NewRegistry(conf ServiceConfig, def string) *Registry {
+	return &Registry{
+		backends: make(map[string]Service),
+		def:      def,
+		conf:     conf,
+	}
+}
+
+// NewFromConfig returns a registry of the backends listed in conf.
+func NewFromConfig(conf ServiceConfig) (*Registry, error) {
+	r := NewRegistry(conf, conf.DefaultBackend)
+	for _, name := range conf.Backends {
+		switch name {
+		case BackendEC2:
+			err := conf.ValidateRegions()
+			if err != nil {
+				return nil, err
+			}
+			r.Register(name, New(conf))
+		case BackendDocker:
+			s, err := NewDockerHost(conf, "")
+			if err != nil {
+				return nil, err
+			}
+			r.Register(name, s)
+		case BackendHostPool:
+			pool, err := newDockerHostPool(conf)
+			if err != nil {
+				return nil, err
+			}
+			r.Register(name, pool)
+		default:
+			return nil, fmt.Errorf("deployment backend %s is not supported", name)
+		}
+	}
+	if _, err := r.Get(r.def); err != nil {
+		return nil, fmt.Errorf("default deployment backend %s is not configured", r.def)
+	}
+	return r, nil
+}
+
+// newDockerHostPool returns a pool of the docker hosts listed in conf.
+func newDockerHostPool(conf ServiceConfig) (*HostPool, error) {
+	pool := NewHostPool(conf)
+	for _, host := range conf.Hosts {
+		parts := strings.SplitN(host, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || strings.Contains(parts[0], "/") {
+			return nil, fmt.Errorf("deployment host %q should be name=address", host)
+		}
+		s, err := NewDockerHost(conf, parts[1])
+		if err != nil {
+			return nil, err
+		}
+		pool.AddHost(parts[0], s)
+	}
+	return pool, nil
+}
+
+// Register adds a backend.
+func (r *Registry) Register(name string, s Service) {
+	r.backends[name] = s
+}
+
+// Get returns the service for a backend, with the empty name meaning the
+// default backend.
+func (r *Registry) Get(name string) (Service, error) {
+	if name == "" {
+		name = r.def
+	}
+	s, ok := r.backends[name]
+	if !ok {
+		return nil, ErrUnknownBackend
+	}
+	return s, nil
+}
+
+// Backends returns the names of the registered backends.
+func (r *Registry) Backends() []string {
+	names := make([]string, 0, len(r.backends))
+	for name := range r.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultBackend returns the backend used for deployments without one.
+func (r *Registry) DefaultBackend() string {
+	return r.def
+}
+
+// RunDeployment runs the deployment on its backend.
+func (r *Registry) RunDeployment(ctx context.Context, deployment models.Deployment, callbackUrl string) (string, error) {
+	s, err := r.Get(deployment.Backend)
+	if err != nil {
+		return "", err
+	}
+	return s.RunDeployment(ctx, deployment, callbackUrl)
+}
+
+// StopDeployment stops the deployment on its backend.
+func (r *Registry) StopDeployment(ctx context.Context, deployment models.Deployment) error {
+	s, err := r.Get(deployment.Backend)
+	if err != nil {
+		return err
+	}
+	return s.StopDeployment(ctx, deployment)
+}
+
+// GetDepDetail does nothing at the moment
+func (r *Registry) GetDepDetail(id int) (string, error) {
+	return "imaginary", nil
+}
+
+// GetDeploymentStream gets the log stream of the deployment from its backend.
+func (r *Registry) GetDeploymentStream(ctx context.Context, deployment models.Deployment) (*cloudwatchlogs.LogStream, error) {
+	s, err := r.Get(deployment.Backend)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDeploymentStream(ctx, deployment)
+}
+
+// DeploymentLogs follows the logs of the deployment on its backend.
+func (r *Registry) DeploymentLogs(ctx context.Context, deployment models.Deployment) (io.ReadCloser, error) {
+	s, err := r.Get(deployment.Backend)
+	if err != nil {
+		return nil, err
+	}
+	if lr, ok := s.(LogReader); ok {
+		return lr.DeploymentLogs(ctx, deployment)
+	}
+	return nil, ErrNoLogStream
+}
+
+// DescribeInstanceStatus asks each backend about its deployments.
+func (r *Registry) DescribeInstanceStatus(ctx context.Context, deployments []models.Deployment) (map[string]string, error) {
+	return r.fanOut(deployments, func(s Service, deps []models.Deployment) (map[string]string, error) {
+		return s.DescribeInstanceStatus(ctx, deps)
+	})
+}
+
+// DescribeInstanceIPs asks each backend about its deployments.
+func (r *Registry) DescribeInstanceIPs(ctx context.Context, deployments []models.Deployment) (map[string]string, error) {
+	return r.fanOut(deployments, func(s Service, deps []models.Deployment) (map[string]string, error) {
+		return s.DescribeInstanceIPs(ctx, deps)
+	})
+}
+
+// GetServiceConfig outputs the configuration of the service
+func (r *Registry) GetServiceConfig() ServiceConfig {
+	return r.conf
+}
+
+// fanOut groups deployments by backend, and merges the results of calling
+// describe on each. Deployments on unknown backends are left out.
+func (r *Registry) fanOut(deployments []models.Deployment, describe func(Service, []models.Deployment) (map[string]string, error)) (map[string]string, error) {
+	byBackend := make(map[string][]models.Deployment)
+	for _, dep := range deployments {
+		name := dep.Backend
+		if name == "" {
+			name = r.def
+		}
+		byBackend[name] = append(byBackend[name], dep)
+	}
+
+	ret := make(map[string]string)
+	for name, deps := range byBackend {
+		s, err := r.Get(name)
+		if err != nil {
+			continue
+		}
+		results, err := describe(s, deps)
+		if err != nil {
+			return ret, err
+		}
+		for id, v := range results {
+			ret[id] = v
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,145 @@
+package deployment
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+)
+
+func TestResolve(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	r := NewRegistry(ServiceConfig{}, BackendEC2)
+	r.Register(BackendEC2, NewMockService(mockCtrl))
+	r.Register(BackendHostPool, NewMockService(mockCtrl))
+
+	cases := []struct {
+		requested []string
+		expected  string
+		err       error
+	}{
+		{nil, BackendEC2, nil},
+		{[]string{"", ""}, BackendEC2, nil},
+		{[]string{"", BackendHostPool}, BackendHostPool, nil},
+		{[]string{BackendEC2, BackendHostPool}, BackendEC2, nil},
+		{[]string{BackendDocker}, "", ErrUnknownBackend},
+	}
+	for _, c := range cases {
+		backend, err := Resolve(r, c.requested...)
+		if backend != c.expected || err != c.err {
+			t.Errorf("Resolve(%v) = %q, %v, expected %q, %v", c.requested, backend, err, c.expected, c.err)
+		}
+	}
+
+	// services without a registry only run on EC2
+	if _, err := Resolve(NewMockService(mockCtrl), BackendDocker); err != ErrUnknownBackend {
+		t.Errorf("Expected %v, got %v", ErrUnknownBackend, err)
+	}
+}
+
+func TestRegistryRoutesByBackend(t *testing.T) {
+	ctx := context.Background()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Service := NewMockService(mockCtrl)
+	poolService := NewMockService(mockCtrl)
+
+	r := NewRegistry(ServiceConfig{}, BackendEC2)
+	r.Register(BackendEC2, ec2Service)
+	r.Register(BackendHostPool, poolService)
+
+	legacy := models.Deployment{ID: "1", InstanceID: "i-1"}
+	onPrem := models.Deployment{ID: "2", InstanceID: "fpga1/abc", Backend: BackendHostPool}
+
+	poolService.EXPECT().RunDeployment(ctx, onPrem, "callback").Return("fpga1/abc", nil)
+	id, err := r.RunDeployment(ctx, onPrem, "callback")
+	if err != nil || id != "fpga1/abc" {
+		t.Errorf("Expected fpga1/abc, got %q, %v", id, err)
+	}
+
+	ec2Service.EXPECT().StopDeployment(ctx, legacy).Return(nil)
+	if err := r.StopDeployment(ctx, legacy); err != nil {
+		t.Error(err)
+	}
+
+	ec2Service.EXPECT().DescribeInstanceStatus(ctx, []models.Deployment{legacy}).
+		Return(map[string]string{"i-1": ec2.InstanceStateNameRunning}, nil)
+	poolService.EXPECT().DescribeInstanceStatus(ctx, []models.Deployment{onPrem}).
+		Return(map[string]string{"fpga1/abc": ec2.InstanceStateNameTerminated}, nil)
+
+	statuses, err := r.DescribeInstanceStatus(ctx, []models.Deployment{legacy, onPrem})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"i-1":       ec2.InstanceStateNameRunning,
+		"fpga1/abc": ec2.InstanceStateNameTerminated,
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected %v, got %v", expected, statuses)
+	}
+
+	_, err = r.RunDeployment(ctx, models.Deployment{Backend: BackendDocker}, "callback")
+	if err != ErrUnknownBackend {
+		t.Errorf("Expected %v, got %v", ErrUnknownBackend, err)
+	}
+}
+
+func TestHostPool(t *testing.T) {
+	ctx := context.Background()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	host1 := NewMockService(mockCtrl)
+	host2 := NewMockService(mockCtrl)
+
+	pool := NewHostPool(ServiceConfig{})
+	if _, err := pool.RunDeployment(ctx, models.Deployment{}, "callback"); err != ErrNoHosts {
+		t.Errorf("Expected %v, got %v", ErrNoHosts, err)
+	}
+
+	pool.AddHost("fpga1", host1)
+	pool.AddHost("fpga2", host2)
+
+	dep := models.Deployment{ID: "1"}
+	host1.EXPECT().RunDeployment(ctx, dep, "callback").Return("abc", nil)
+	host2.EXPECT().RunDeployment(ctx, dep, "callback").Return("def", nil)
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		id, err := pool.RunDeployment(ctx, dep, "callback")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if !reflect.DeepEqual(ids, []string{"fpga1/abc", "fpga2/def"}) {
+		t.Errorf("Expected deployments on each host, got %v", ids)
+	}
+
+	host2.EXPECT().StopDeployment(ctx, models.Deployment{ID: "1", InstanceID: "def"}).Return(nil)
+	if err := pool.StopDeployment(ctx, models.Deployment{ID: "1", InstanceID: "fpga2/def"}); err != nil {
+		t.Error(err)
+	}
+
+	host1.EXPECT().DescribeInstanceIPs(ctx, []models.Deployment{{InstanceID: "abc"}}).
+		Return(map[string]string{"abc": "10.0.0.1"}, nil)
+	ips, err := pool.DescribeInstanceIPs(ctx, []models.Deployment{
+		{InstanceID: "fpga1/abc"},
+		{InstanceID: "gone/xyz"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ips, map[string]string{"fpga1/abc": "10.0.0.1"}) {
+		t.Errorf("Unexpected IPs %v", ips)
+	}
+}
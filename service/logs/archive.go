@@ -0,0 +1,80 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ReconfigureIO/platform/service/storage"
+)
+
+// Archive is a LogSource of logs archived to storage once their job has
+// finished, such as by fake-batch, which serves jobs' logs, archived or
+// not, by their batch IDs.
+type Archive struct {
+	// Storage is where logs are archived, by name, if it's reachable.
+	Storage storage.Service
+	// Endpoint is fake-batch's URL, if logs are served by it.
+	Endpoint string
+}
+
+var _ LogSource = &Archive{}
+
+// Stream returns the archived logs, falling back to fake-batch for logs
+// which aren't archived yet, as they are now.
+func (a *Archive) Stream(ctx context.Context, name string) (io.ReadCloser, error) {
+	if a.Storage != nil {
+		r, err := a.Storage.Download(name)
+		if err == nil || a.Endpoint == "" {
+			return r, err
+		}
+	}
+	return a.serve(ctx, name, false)
+}
+
+// Range returns lines of the logs.
+func (a *Archive) Range(ctx context.Context, name string, from int, to int) ([]string, error) {
+	return streamRange(ctx, a, name, from, to)
+}
+
+// Follow follows the logs with fake-batch, which serves them until their
+// job finishes, or returns them as Stream would without it. It is valid
+// to call Follow on logs which do not yet exist, in that case, fake-batch
+// will wait for them to exist, or for the context to be canceled.
+func (a *Archive) Follow(ctx context.Context, name string, finished func() bool) (io.ReadCloser, error) {
+	if a.Endpoint == "" {
+		return a.Stream(ctx, name)
+	}
+	return a.serve(ctx, name, true)
+}
+
+// serve requests the logs from fake-batch, which follows the logs of
+// running jobs if follow is true.
+func (a *Archive) serve(ctx context.Context, name string, follow bool) (io.ReadCloser, error) {
+	url := fmt.Sprintf("%s/v1/logs/%s", a.Endpoint, name)
+	if !follow {
+		url += "?follow=false"
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("logs.Archive: http.NewRequest: %v", err)
+	}
+
+	req = req.WithContext(ctx)
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("logs.Archive: client.Do: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if !(200 <= resp.StatusCode && resp.StatusCode <= 299) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("logs.Archive: client.Do: non-2xx status: %v %v", resp.StatusCode, resp.Status)
+	}
+
+	return resp.Body, nil
+}
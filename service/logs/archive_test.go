@@ -0,0 +1,59 @@
+package logs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs/job" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("foo\nbar\n"))
+		if r.URL.Query().Get("follow") == "false" {
+			return
+		}
+		// followed logs of a running job don't end
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer s.Close()
+
+	a := &Archive{Endpoint: s.URL}
+	ctx := context.Background()
+
+	followCtx, cancel := context.WithCancel(ctx)
+	rc, err := a.Follow(followCtx, "job", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, len("foo\nbar\n"))
+	_, err = io.ReadFull(rc, out)
+	cancel()
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "foo\nbar\n" {
+		t.Errorf("Unexpected logs %q", out)
+	}
+
+	// ranges of a running job's logs return what's there so far
+	lines, err := a.Range(ctx, "job", 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"foo", "bar"}) {
+		t.Errorf("Unexpected lines %q", lines)
+	}
+
+	if _, err := a.Stream(ctx, "other"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
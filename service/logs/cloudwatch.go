@@ -1,10 +1,10 @@
-// Package logs implements (*logs.Service).Stream(ctx, logStreamName) io.ReadCloser backed by polling CloudWatchLogs.
-package cloudwatchlogs
+package logs
 
 import (
 	"bytes"
 	"context"
 	"io"
+	"io/ioutil"
 	"log"
 	"strings"
 	"time"
@@ -14,9 +14,10 @@ import (
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
 )
 
-// Service implements Stream(logStreamName string) io.ReadCloser.
-// It polls the CloudWatchLogs API at a period defined by defaultPollPeriod.
-type Service struct {
+// CloudWatch is a LogSource of the log streams in a CloudWatch Logs log
+// group. Logs are followed by polling the CloudWatchLogs API at a period
+// defined by defaultPollPeriod.
+type CloudWatch struct {
 	CloudWatchLogsAPI cloudwatchlogsiface.CloudWatchLogsAPI
 	LogGroup          string
 
@@ -25,25 +26,59 @@ type Service struct {
 
 const defaultPollPeriod = 10 * time.Second
 
-func (s *Service) pollPeriod() time.Duration {
+func (s *CloudWatch) pollPeriod() time.Duration {
 	if s._pollPeriod == time.Duration(0) {
 		return defaultPollPeriod
 	}
 	return s._pollPeriod
 }
 
-// Stream returns an io.ReadCloser containing the logs for the given
+var _ LogSource = &CloudWatch{}
+
+// Stream returns the log stream's events so far, one per line.
+func (s *CloudWatch) Stream(ctx context.Context, logStreamName string) (io.ReadCloser, error) {
+	var buf, scratchBuf bytes.Buffer
+	req := (&cloudwatchlogs.GetLogEventsInput{}).
+		SetLogGroupName(s.LogGroup).
+		SetLogStreamName(logStreamName).
+		SetStartFromHead(true)
+
+	var err2 error // For tracking write errors.
+	err := s.CloudWatchLogsAPI.GetLogEventsPagesWithContext(ctx, req,
+		func(resp *cloudwatchlogs.GetLogEventsOutput, lastPage bool) bool {
+			err2 = writeEvents(&scratchBuf, &buf, resp)
+			// CloudWatch returns empty pages once it's caught up.
+			return err2 == nil && len(resp.Events) > 0 && !lastPage
+		})
+	if isResourceNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(&buf), nil
+}
+
+// Range returns lines of the log stream's events so far.
+func (s *CloudWatch) Range(ctx context.Context, logStreamName string, from int, to int) ([]string, error) {
+	return streamRange(ctx, s, logStreamName, from, to)
+}
+
+// Follow returns an io.ReadCloser containing the logs for the given
 // logStreamName. Under the hood, it polls CloudWatchLogs. It is valid to call
-// Stream on a logStreamName which does not yet exist, in that case, Stream will
+// Follow on a logStreamName which does not yet exist, in that case, Follow will
 // wait for it to exist, or for the context to be canceled. Context cancelation
 // is treated as the end of the stream, causing the ReadCloser to return io.EOF.
-func (s *Service) Stream(ctx context.Context, logStreamName string) io.ReadCloser {
+func (s *CloudWatch) Follow(ctx context.Context, logStreamName string, finished func() bool) (io.ReadCloser, error) {
 	r, w := io.Pipe()
-	go s.pollCloudWatch(ctx, w, logStreamName)
-	return r
+	go s.pollCloudWatch(ctx, w, logStreamName, finished)
+	return r, nil
 }
 
-func (s *Service) pollCloudWatch(ctx context.Context, w *io.PipeWriter, logStreamName string) {
+func (s *CloudWatch) pollCloudWatch(ctx context.Context, w *io.PipeWriter, logStreamName string, finished func() bool) {
 	pollTimer := time.NewTimer(1 * time.Hour)
 	if !pollTimer.Stop() {
 		// Unlikely, due to the 1h duration chosen above but correct in spirit.
@@ -70,6 +105,11 @@ func (s *Service) pollCloudWatch(ctx context.Context, w *io.PipeWriter, logStrea
 				return false // Stop.
 			}
 
+			// Caught up with a finished job's logs.
+			if len(resp.Events) == 0 && finished != nil && finished() {
+				return false // Stop.
+			}
+
 			// Start the timer now.
 			pollTimer.Reset(s.pollPeriod())
 
@@ -93,7 +133,7 @@ func (s *Service) pollCloudWatch(ctx context.Context, w *io.PipeWriter, logStrea
 
 	err = w.CloseWithError(err)
 	if err != nil {
-		log.Printf("aws/logs/Service.Stream: w.CloseWithError: %v", err)
+		log.Printf("logs.CloudWatch.Follow: w.CloseWithError: %v", err)
 	}
 }
 
@@ -1,4 +1,4 @@
-package cloudwatchlogs
+package logs
 
 import (
 	"context"
@@ -40,7 +40,7 @@ func (testCase testCasePages) run(t *testing.T) {
 	// Leaky goroutines check.
 	defer leaktest.Check(t)()
 
-	s := Service{
+	s := CloudWatch{
 		CloudWatchLogsAPI: &fakeCloudWatchLogsPages{
 			pageToOutputLogEvents: stringsToPageToOutputLogEvents([][]string(testCase)),
 		},
@@ -51,7 +51,10 @@ func (testCase testCasePages) run(t *testing.T) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	rc := s.Stream(ctx, "testLogStreamName")
+	rc, err := s.Follow(ctx, "testLogStreamName", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer func() {
 		err2 := rc.Close()
 		if err2 != nil {
@@ -111,7 +114,7 @@ func TestCloudWatchError(t *testing.T) {
 
 	errTest := errors.New("errTest")
 
-	s := Service{
+	s := CloudWatch{
 		CloudWatchLogsAPI: &fakeCloudWatchLogsError{
 			// GetLogEventsPagesWithContext returns errTest.
 			err: errTest,
@@ -123,9 +126,12 @@ func TestCloudWatchError(t *testing.T) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	rc := s.Stream(ctx, "testLogStreamName")
+	rc, err := s.Follow(ctx, "testLogStreamName", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	_, err := io.Copy(ioutil.Discard, rc)
+	_, err = io.Copy(ioutil.Discard, rc)
 	if err != errTest {
 		t.Fatalf("err != errTest: (err is %v)", err)
 	}
@@ -143,7 +149,7 @@ func TestCloudWatchTermination(t *testing.T) {
 	// Leaky goroutines check.
 	defer leaktest.Check(t)()
 
-	s := Service{
+	s := CloudWatch{
 		// Infinite stream of empty pages.
 		CloudWatchLogsAPI: &fakeCloudWatchLogInfinite{},
 		// Poll at an extremely high frequency.
@@ -156,9 +162,12 @@ func TestCloudWatchTermination(t *testing.T) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	rc := s.Stream(ctx, "testLogStreamName")
+	rc, err := s.Follow(ctx, "testLogStreamName", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	_, err := io.Copy(ioutil.Discard, rc)
+	_, err = io.Copy(ioutil.Discard, rc)
 	if err != nil {
 		t.Fatalf("io.Copy: %v ", err)
 	}
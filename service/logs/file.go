@@ -0,0 +1,109 @@
+package logs
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File is a LogSource of log files in a directory, such as one jobs on
+// the same host, or a shared volume, write their logs to. Logs are
+// followed by polling the file at a period defined by defaultPollPeriod.
+type File struct {
+	Dir string
+
+	_pollPeriod time.Duration // for tests only.
+}
+
+var _ LogSource = &File{}
+
+func (f *File) pollPeriod() time.Duration {
+	if f._pollPeriod == time.Duration(0) {
+		return defaultPollPeriod
+	}
+	return f._pollPeriod
+}
+
+// path returns the path of the log file named name, which can't be
+// outside Dir.
+func (f *File) path(name string) string {
+	return filepath.Join(f.Dir, filepath.Clean("/"+name))
+}
+
+// Stream opens the log file.
+func (f *File) Stream(ctx context.Context, name string) (io.ReadCloser, error) {
+	file, err := os.Open(f.path(name))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Range returns lines of the log file.
+func (f *File) Range(ctx context.Context, name string, from int, to int) ([]string, error) {
+	return streamRange(ctx, f, name, from, to)
+}
+
+// Follow reads the log file as it's written, like tail -f. It is valid to
+// call Follow on a file which does not yet exist, in that case, Follow
+// will wait for it to exist, or for the context to be canceled. Context
+// cancelation is treated as the end of the logs.
+func (f *File) Follow(ctx context.Context, name string, finished func() bool) (io.ReadCloser, error) {
+	r, w := io.Pipe()
+	go f.tail(ctx, w, name, finished)
+	return r, nil
+}
+
+func (f *File) tail(ctx context.Context, w *io.PipeWriter, name string, finished func() bool) {
+	ticker := time.NewTicker(f.pollPeriod())
+	defer ticker.Stop()
+
+	// wait reports if there may be more to read after waiting a poll
+	// period.
+	wait := func() bool {
+		if finished != nil && finished() {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			return true
+		}
+	}
+
+	var file *os.File
+	for file == nil {
+		var err error
+		file, err = os.Open(f.path(name))
+		if err != nil && !os.IsNotExist(err) {
+			w.CloseWithError(err)
+			return
+		}
+		if err != nil && !wait() {
+			w.Close()
+			return
+		}
+	}
+	defer file.Close()
+
+	for {
+		_, err := io.Copy(w, file)
+		if err != nil {
+			w.CloseWithError(err)
+			return
+		}
+		// caught up with the file, so wait for more to be written
+		if !wait() {
+			break
+		}
+	}
+	// the file may have been written to before the job finished
+	_, err := io.Copy(w, file)
+	w.CloseWithError(err)
+}
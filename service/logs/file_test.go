@@ -0,0 +1,91 @@
+package logs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := &File{Dir: dir, _pollPeriod: time.Millisecond}
+	ctx := context.Background()
+
+	if _, err := f.Stream(ctx, "job"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "job"), []byte("foo\nbar\nbaz\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := f.Range(ctx, "job", 1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"bar", "baz"}) {
+		t.Errorf("Unexpected lines %q", lines)
+	}
+
+	// names can't escape the directory
+	if _, err := f.Stream(ctx, "../"+filepath.Base(dir)+"/job"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFileFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := &File{Dir: dir, _pollPeriod: time.Millisecond}
+	done := make(chan struct{})
+	finished := func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+
+	// the file doesn't exist until after Follow is called
+	rc, err := f.Follow(context.Background(), "job", finished)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	file, err := os.Create(filepath.Join(dir, "job"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	go func() {
+		file.WriteString("hello\n")
+		time.Sleep(10 * time.Millisecond)
+		file.WriteString("world\n")
+		close(done)
+	}()
+
+	out, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hello\nworld\n" {
+		t.Errorf("Unexpected logs %q", out)
+	}
+}
@@ -0,0 +1,56 @@
+// Package logs reads the logs of jobs, such as builds and deployments,
+// from wherever they're kept.
+package logs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned for logs which don't exist.
+var ErrNotFound = errors.New("logs not found")
+
+// LogSource is somewhere job logs are kept, by name, e.g. CloudWatch log
+// streams or files.
+type LogSource interface {
+	// Stream returns the logs named name, as they are now.
+	Stream(ctx context.Context, name string) (io.ReadCloser, error)
+	// Range returns lines from up to, but not including, to of the logs
+	// named name. A negative to means the end of the logs.
+	Range(ctx context.Context, name string, from int, to int) ([]string, error)
+	// Follow returns the logs named name, and what's added to them, until
+	// ctx is done or finished returns true once no more have been added.
+	// The logs are waited for if they don't exist yet. A nil finished
+	// follows the logs until ctx is done, or their source ends them.
+	Follow(ctx context.Context, name string, finished func() bool) (io.ReadCloser, error)
+}
+
+// ReadRange reads lines from up to, but not including, to from r. A
+// negative to reads to the end of r. It's for LogSources whose logs can
+// only be read from the start.
+func ReadRange(r io.Reader, from int, to int) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for i := 0; scanner.Scan(); i++ {
+		if to >= 0 && i >= to {
+			break
+		}
+		if i >= from {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// streamRange returns a range of the lines Stream returns from s.
+func streamRange(ctx context.Context, s LogSource, name string, from int, to int) ([]string, error) {
+	r, err := s.Stream(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ReadRange(r, from, to)
+}
@@ -0,0 +1,136 @@
+// Package notify fans out Postgres NOTIFY messages to in-process
+// subscribers.
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// BatchJobEvents is notified with the batch job ID whenever a batch
+	// job event is stored.
+	BatchJobEvents = "batch_job_events"
+	// DeploymentEvents is notified with the deployment ID whenever a
+	// deployment event is stored.
+	DeploymentEvents = "deployment_events"
+	// QueueEntries is notified with the job type whenever a job is pushed
+	// onto a queue, or leaves a queue slot free.
+	QueueEntries = "queue_entries"
+)
+
+// Hub listens to Postgres channels, and signals the subscribers of each
+// notification's payload.
+type Hub struct {
+	listener *pq.Listener
+
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+// Subscription is signalled on C whenever its channel is notified with its
+// key. Signals are coalesced, so a subscriber should recheck state rather
+// than count signals.
+type Subscription struct {
+	C chan struct{}
+
+	hub   *Hub
+	topic string
+}
+
+// New starts a Hub listening to channels on the database at dbURL.
+func New(dbURL string, channels ...string) (*Hub, error) {
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.WithError(err).Error("postgres listener")
+		}
+	})
+	for _, channel := range channels {
+		if err := listener.Listen(channel); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	hub := newHub()
+	hub.listener = listener
+	go hub.run(listener.Notify)
+	return hub, nil
+}
+
+func newHub() *Hub {
+	return &Hub{subs: make(map[string]map[*Subscription]struct{})}
+}
+
+func (h *Hub) run(notifications <-chan *pq.Notification) {
+	for n := range notifications {
+		if n == nil {
+			// the connection was re-established, and notifications may
+			// have been missed, so everyone needs to recheck
+			h.broadcast()
+			continue
+		}
+		h.publish(n.Channel, n.Extra)
+	}
+}
+
+// Subscribe returns a subscription to notifications on channel with
+// payload key. It must be closed when no longer needed.
+func (h *Hub) Subscribe(channel string, key string) *Subscription {
+	sub := &Subscription{
+		C:     make(chan struct{}, 1),
+		hub:   h,
+		topic: channel + ":" + key,
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[sub.topic] == nil {
+		h.subs[sub.topic] = make(map[*Subscription]struct{})
+	}
+	h.subs[sub.topic][sub] = struct{}{}
+	return sub
+}
+
+// Close unsubscribes.
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[s.topic], s)
+	if len(h.subs[s.topic]) == 0 {
+		delete(h.subs, s.topic)
+	}
+}
+
+func (s *Subscription) signal() {
+	select {
+	case s.C <- struct{}{}:
+	default:
+	}
+}
+
+func (h *Hub) publish(channel string, key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs[channel+":"+key] {
+		sub.signal()
+	}
+}
+
+func (h *Hub) broadcast() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.subs {
+		for sub := range subs {
+			sub.signal()
+		}
+	}
+}
+
+// Close stops listening.
+func (h *Hub) Close() error {
+	return h.listener.Close()
+}
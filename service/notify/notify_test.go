@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func signalled(sub *Subscription) bool {
+	select {
+	case <-sub.C:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := newHub()
+	notifications := make(chan *pq.Notification)
+	go hub.run(notifications)
+
+	sub := hub.Subscribe(BatchJobEvents, "1")
+	other := hub.Subscribe(DeploymentEvents, "1")
+	defer other.Close()
+
+	// an unbuffered send only returns once run has received it, the
+	// second send ensures the first was handled
+	notifications <- &pq.Notification{Channel: BatchJobEvents, Extra: "1"}
+	notifications <- &pq.Notification{Channel: BatchJobEvents, Extra: "2"}
+
+	if !signalled(sub) {
+		t.Error("Expected subscriber to be signalled")
+	}
+	if signalled(other) {
+		t.Error("Expected subscriber of another channel not to be signalled")
+	}
+
+	sub.Close()
+	notifications <- &pq.Notification{Channel: BatchJobEvents, Extra: "1"}
+	notifications <- nil
+	notifications <- &pq.Notification{Channel: BatchJobEvents, Extra: "2"}
+	close(notifications)
+
+	if signalled(sub) {
+		t.Error("Expected closed subscription not to be signalled")
+	}
+	if !signalled(other) {
+		t.Error("Expected reconnection to signal every subscriber")
+	}
+}
@@ -0,0 +1,223 @@
+package queue
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/batch"
+	"github.com/ReconfigureIO/platform/service/batchretry"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultBatchPollInterval is how often BatchRunner checks if a batch job
+// has finished.
+const defaultBatchPollInterval = 30 * time.Second
+
+// BatchRunner is queue job runner implementation for builds, simulations
+// and graphs, which run as batch jobs. Run submits a job's batch job, and
+// waits for it to finish, so the job holds its slot in the queue, and
+// counts towards its user's limit, while it runs.
+//
+// Jobs are pushed with a batch job which has no batch ID, and a QUEUED
+// event, which is given its batch ID once submitted.
+type BatchRunner struct {
+	Type         string // TypeBuild, TypeSimulation or TypeGraph
+	APIBaseURL   url.URL
+	Batch        batch.Service
+	DB           *gorm.DB
+	PollInterval time.Duration
+	// Retrier handles batch jobs which fail without reporting it. Without
+	// one they're marked as errored.
+	Retrier *batchretry.Retrier
+}
+
+var _ JobRunner = BatchRunner{}
+
+// callbackURL returns the URL of path, authenticated with token.
+func (r BatchRunner) callbackURL(path string, token string) string {
+	u := r.APIBaseURL
+	u.Path = path
+	u.RawQuery = fmt.Sprintf("token=%s", token)
+	return u.String()
+}
+
+// load loads a job's batch job, and returns a func to submit it.
+func (r BatchRunner) load(id string) (models.BatchJob, func() (string, error), error) {
+	preload := r.DB.Preload("BatchJob").Preload("BatchJob.Events", func(db *gorm.DB) *gorm.DB {
+		return db.Order("timestamp ASC")
+	})
+
+	switch r.Type {
+	case TypeBuild:
+		var build models.Build
+		err := preload.Preload("Project").First(&build, "id = ?", id).Error
+		return build.BatchJob, func() (string, error) {
+			return r.Batch.RunBuild(build,
+				r.callbackURL("/builds/"+build.ID+"/events", build.Token),
+				r.callbackURL("/builds/"+build.ID+"/reports", build.Token),
+			)
+		}, err
+	case TypeSimulation:
+		var sim models.Simulation
+		err := preload.First(&sim, "id = ?", id).Error
+		return sim.BatchJob, func() (string, error) {
+			return r.Batch.RunSimulation(sim,
+				r.callbackURL("/simulations/"+sim.ID+"/events", sim.Token),
+				r.callbackURL("/simulations/"+sim.ID+"/reports", sim.Token),
+			)
+		}, err
+	case TypeGraph:
+		var graph models.Graph
+		err := preload.First(&graph, "id = ?", id).Error
+		return graph.BatchJob, func() (string, error) {
+			return r.Batch.RunGraph(graph, r.callbackURL("/graphs/"+graph.ID+"/events", graph.Token))
+		}, err
+	}
+	return models.BatchJob{}, nil, fmt.Errorf("queue: no batch jobs of type %q", r.Type)
+}
+
+// Resubmit submits a job's batch job again, with the same input, and
+// returns its new batch ID.
+func (r BatchRunner) Resubmit(id string) (string, error) {
+	_, submit, err := r.load(id)
+	if err != nil {
+		return "", err
+	}
+	return submit()
+}
+
+// Run satisifies queue.JobRunner interface.
+func (r BatchRunner) Run(j Job) {
+	logger := log.WithFields(log.Fields{"type": r.Type, "id": j.ID})
+	batchJob, submit, err := r.load(j.ID)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	// cancelled while queued
+	if batchJob.HasFinished() {
+		return
+	}
+
+	repo := models.BatchDataSource(r.DB)
+	// the job may be run again if it was lost, but is only submitted once
+	if batchJob.BatchID == "" {
+		batchID, err := submit()
+		if err != nil {
+			logger.WithError(err).Error("Unable to submit batch job")
+			err = repo.AddEvent(batchJob, models.BatchJobEvent{
+				Timestamp: time.Now(),
+				Status:    models.StatusErrored,
+				Message:   "Unable to start job",
+			})
+			if err != nil {
+				logger.Error(err)
+			}
+			return
+		}
+		err = repo.SetBatchID(batchJob, batchID)
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+		batchJob.BatchID = batchID
+
+		// a job cancelled while it was submitted had no batch ID to halt
+		current, err := r.reload(batchJob)
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+		if current.HasFinished() {
+			err = r.Batch.HaltJob(batchID)
+			if err != nil {
+				logger.Error(err)
+			}
+			return
+		}
+	}
+
+	err = r.wait(batchJob)
+	if err != nil {
+		logger.Error(err)
+	}
+}
+
+// reload loads batchJob again, with its events and attempts.
+func (r BatchRunner) reload(batchJob models.BatchJob) (models.BatchJob, error) {
+	var current models.BatchJob
+	err := r.DB.Preload("Events", func(db *gorm.DB) *gorm.DB {
+		return db.Order("timestamp ASC")
+	}).Preload("Attempts").First(&current, batchJob.ID).Error
+	return current, err
+}
+
+// wait blocks until the batch job has finished, going by its events, or
+// by its batch backend in case its last events were never sent. Jobs which
+// failed are either retried, as a new batch job, and waited on, or marked
+// as errored.
+func (r BatchRunner) wait(batchJob models.BatchJob) error {
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = defaultBatchPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		current, err := r.reload(batchJob)
+		if err != nil {
+			return err
+		}
+		if current.HasFinished() {
+			return nil
+		}
+
+		detail, err := r.Batch.GetJobDetail(current.BatchID)
+		if err != nil {
+			return err
+		}
+		if !detail.HasFinished() {
+			continue
+		}
+		switch detail.Status {
+		case models.StatusCompleted:
+			return nil
+		case models.StatusErrored:
+			if r.Retrier != nil {
+				err = r.Retrier.Handle(current, detail)
+				if err != nil {
+					return err
+				}
+				// retried jobs are waited on by their new batch ID
+				continue
+			}
+		}
+		return models.BatchDataSource(r.DB).AddEvent(current, models.BatchJobEvent{
+			Timestamp: time.Now(),
+			Status:    detail.Status,
+			Message:   detail.Reason,
+			Code:      detail.ExitCode,
+		})
+	}
+	return nil
+}
+
+// Stop satisifies queue.JobRunner interface.
+func (r BatchRunner) Stop(j Job) {
+	batchJob, _, err := r.load(j.ID)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if batchJob.BatchID == "" {
+		return
+	}
+	err = r.Batch.HaltJob(batchJob.BatchID)
+	if err != nil {
+		log.Error(err)
+	}
+}
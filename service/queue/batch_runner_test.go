@@ -0,0 +1,146 @@
+// +build integration
+
+package queue
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/batch"
+	"github.com/dchest/uniuri"
+	"github.com/golang/mock/gomock"
+)
+
+func TestBatchRunnerBuild(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	db := connectDB()
+	repo := models.BatchDataSource(db)
+	build := models.Build{
+		Project:  models.Project{UserID: "batch-runner-user"},
+		Token:    uniuri.NewLen(64),
+		BatchJob: repo.New(""),
+	}
+	if err := db.Create(&build).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	batchService := batch.NewMockService(mockCtrl)
+	batchService.EXPECT().
+		RunBuild(gomock.Any(), "https://api.test/builds/"+build.ID+"/events?token="+build.Token, gomock.Any()).
+		Return("batch-runner-1", nil)
+	batchService.EXPECT().
+		GetJobDetail("batch-runner-1").
+		Return(batch.JobDetail{Status: models.StatusStarted}, nil).
+		AnyTimes()
+
+	runner := BatchRunner{
+		Type:         TypeBuild,
+		APIBaseURL:   url.URL{Scheme: "https", Host: "api.test"},
+		Batch:        batchService,
+		DB:           db,
+		PollInterval: 10 * time.Millisecond,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		runner.Run(Job{ID: build.ID})
+		close(done)
+	}()
+
+	// the build holds its slot until it finishes
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Run returned before the batch job finished")
+	default:
+	}
+
+	var batchJob models.BatchJob
+	if err := db.First(&batchJob, build.BatchJob.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if batchJob.BatchID != "batch-runner-1" {
+		t.Errorf("Expected batch ID batch-runner-1, got %q", batchJob.BatchID)
+	}
+
+	err := repo.AddEvent(batchJob, models.BatchJobEvent{Timestamp: time.Now(), Status: models.StatusCompleted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return once the batch job finished")
+	}
+}
+
+func TestBatchRunnerCancelled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	db := connectDB()
+	repo := models.BatchDataSource(db)
+	graph := models.Graph{
+		Project:  models.Project{UserID: "batch-runner-user"},
+		Token:    uniuri.NewLen(64),
+		BatchJob: repo.New(""),
+	}
+	if err := db.Create(&graph).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := repo.AddEvent(graph.BatchJob, models.BatchJobEvent{Timestamp: time.Now(), Status: models.StatusTerminated})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no calls are expected of the batch service
+	runner := BatchRunner{
+		Type:  TypeGraph,
+		Batch: batch.NewMockService(mockCtrl),
+		DB:    db,
+	}
+	runner.Run(Job{ID: graph.ID})
+}
+
+func TestBatchRunnerCancelledWhileSubmitting(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	db := connectDB()
+	repo := models.BatchDataSource(db)
+	build := models.Build{
+		Project:  models.Project{UserID: "batch-runner-user"},
+		Token:    uniuri.NewLen(64),
+		BatchJob: repo.New(""),
+	}
+	if err := db.Create(&build).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// the build is cancelled before its batch ID is recorded, so only the
+	// runner can halt it
+	batchService := batch.NewMockService(mockCtrl)
+	batchService.EXPECT().
+		RunBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(models.Build, string, string) {
+			err := repo.AddEvent(build.BatchJob, models.BatchJobEvent{Timestamp: time.Now(), Status: models.StatusTerminated})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}).
+		Return("batch-runner-2", nil)
+	batchService.EXPECT().HaltJob("batch-runner-2").Return(nil)
+
+	runner := BatchRunner{
+		Type:         TypeBuild,
+		APIBaseURL:   url.URL{Scheme: "https", Host: "api.test"},
+		Batch:        batchService,
+		DB:           db,
+		PollInterval: 10 * time.Millisecond,
+	}
+	runner.Run(Job{ID: build.ID})
+}
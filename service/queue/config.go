@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultConcurrency is how many jobs of a type missing from
+// Config.Concurrency run at once.
+const DefaultConcurrency = 1
+
+// Config configures the queues.
+type Config struct {
+	// Concurrency is how many jobs of each type run at once, as
+	// type=count, e.g. deployment=2.
+	Concurrency []string `env:"RECO_QUEUE_CONCURRENCY" envDefault:"deployment=2,build=20,simulation=20,graph=20"`
+	// MaxPerUser is how many jobs of a type each user may have running at
+	// once, so that one user can't take every slot. 0 is unlimited.
+	MaxPerUser int `env:"RECO_QUEUE_MAX_PER_USER" envDefault:"1"`
+	// MaxPerUserTypes overrides MaxPerUser for job types, as type=count.
+	MaxPerUserTypes []string `env:"RECO_QUEUE_MAX_PER_USER_TYPES" envDefault:"build=2,simulation=2,graph=2"`
+	// PollSeconds is how often queues check for jobs they weren't told
+	// about.
+	PollSeconds int `env:"RECO_QUEUE_POLL_SECONDS" envDefault:"60"`
+	// HeartbeatSeconds is how often replicas report the jobs they're
+	// running are still running.
+	HeartbeatSeconds int `env:"RECO_QUEUE_HEARTBEAT_SECONDS" envDefault:"60"`
+	// StuckAfterMinutes is how long a job may go without a heartbeat
+	// before it's presumed lost with the replica running it, and is
+	// queued again. It must be several heartbeats long. 0 never queues
+	// jobs again.
+	StuckAfterMinutes int `env:"RECO_QUEUE_STUCK_AFTER_MINUTES" envDefault:"10"`
+}
+
+// ConcurrencyOf returns how many jobs of jobType run at once.
+func (c Config) ConcurrencyOf(jobType string) int {
+	n, ok := lookupType(c.Concurrency, jobType)
+	if !ok || n < 1 {
+		return DefaultConcurrency
+	}
+	return n
+}
+
+// MaxPerUserOf returns how many jobs of jobType each user may have
+// running at once, 0 being unlimited.
+func (c Config) MaxPerUserOf(jobType string) int {
+	n, ok := lookupType(c.MaxPerUserTypes, jobType)
+	if !ok || n < 0 {
+		return c.MaxPerUser
+	}
+	return n
+}
+
+// lookupType finds jobType's count in a list of type=count.
+func lookupType(counts []string, jobType string) (int, bool) {
+	for _, v := range counts {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] != jobType {
+			continue
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
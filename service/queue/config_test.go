@@ -0,0 +1,34 @@
+package queue
+
+import "testing"
+
+func TestConfigConcurrencyOf(t *testing.T) {
+	conf := Config{Concurrency: []string{"deployment=4", "build=0", "simulation", "graph=x"}}
+	expected := map[string]int{
+		"deployment": 4,
+		"build":      DefaultConcurrency,
+		"simulation": DefaultConcurrency,
+		"graph":      DefaultConcurrency,
+		"other":      DefaultConcurrency,
+	}
+	for jobType, n := range expected {
+		if c := conf.ConcurrencyOf(jobType); c != n {
+			t.Errorf("Expected concurrency of %s to be %d, got %d", jobType, n, c)
+		}
+	}
+}
+
+func TestConfigMaxPerUserOf(t *testing.T) {
+	conf := Config{MaxPerUser: 1, MaxPerUserTypes: []string{"build=3", "graph=0", "simulation=-1"}}
+	expected := map[string]int{
+		"build":      3,
+		"graph":      0,
+		"simulation": 1,
+		"deployment": 1,
+	}
+	for jobType, n := range expected {
+		if c := conf.MaxPerUserOf(jobType); c != n {
+			t.Errorf("Expected max per user of %s to be %d, got %d", jobType, n, c)
+		}
+	}
+}
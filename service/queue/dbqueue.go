@@ -1,25 +1,41 @@
 package queue
 
 import (
-	"sync"
 	"time"
 
 	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/notify"
 	"github.com/jinzhu/gorm"
 	log "github.com/sirupsen/logrus"
 )
 
 var _ Queue = &dbQueue{}
 
+// defaultStuckAfter is how long jobs of queues not made from a Config may
+// go without a heartbeat before they're presumed lost.
+const defaultStuckAfter = 10 * time.Minute
+
+// defaultHeartbeat is how often jobs of queues not made from a Config are
+// reported running.
+const defaultHeartbeat = time.Minute
+
 type dbQueue struct {
 	jobType      string
 	runner       JobRunner
 	concurrent   int
+	maxPerUser   int
 	service      QueueService
 	pollInterval time.Duration
+	heartbeat    time.Duration
+	stuckAfter   time.Duration
+
+	// wake is signalled when a slot might be free, or a job pushed.
+	wake chan struct{}
+	// notified is signalled when another replica pushes or finishes a
+	// job, if the queue listens for notifications.
+	notified *notify.Subscription
 
 	halt chan struct{}
-	once sync.Once
 }
 
 // NewWithDBStore creates a new queue using database as storage for queue state.
@@ -30,12 +46,57 @@ func NewWithDBStore(db *gorm.DB, runner JobRunner, concurrent int, jobType strin
 		concurrent:   concurrent,
 		service:      QueueService{db: db},
 		pollInterval: time.Second * 60,
+		heartbeat:    defaultHeartbeat,
+		stuckAfter:   defaultStuckAfter,
+		wake:         make(chan struct{}, 1),
+		halt:         make(chan struct{}),
+	}
+}
+
+// NewFromConfig creates a new queue using database as storage for queue
+// state, running as many jobs at once as conf allows. If hub isn't nil,
+// the queue is woken by jobs pushed and finished by other replicas, as
+// well as its own.
+func NewFromConfig(db *gorm.DB, runner JobRunner, jobType string, conf Config, hub *notify.Hub) Queue {
+	q := &dbQueue{
+		jobType:      jobType,
+		runner:       runner,
+		concurrent:   conf.ConcurrencyOf(jobType),
+		maxPerUser:   conf.MaxPerUserOf(jobType),
+		service:      QueueService{db: db},
+		pollInterval: time.Duration(conf.PollSeconds) * time.Second,
+		heartbeat:    time.Duration(conf.HeartbeatSeconds) * time.Second,
+		stuckAfter:   time.Duration(conf.StuckAfterMinutes) * time.Minute,
+		wake:         make(chan struct{}, 1),
 		halt:         make(chan struct{}),
 	}
+	if q.pollInterval <= 0 {
+		q.pollInterval = time.Second * 60
+	}
+	if q.heartbeat <= 0 {
+		q.heartbeat = defaultHeartbeat
+	}
+	if hub != nil {
+		q.notified = hub.Subscribe(notify.QueueEntries, jobType)
+	}
+	return q
+}
+
+// signal wakes the dispatch loop, unless it's already due to wake.
+func (d *dbQueue) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
 }
 
 func (d *dbQueue) Push(job Job) {
-	d.service.Push(d.jobType, job)
+	err := d.service.Push(d.jobType, job)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	d.signal()
 }
 
 func (d *dbQueue) CountUserJobsInStatus(user models.User, status string) (int, error) {
@@ -43,35 +104,74 @@ func (d *dbQueue) CountUserJobsInStatus(user models.User, status string) (int, e
 	return count, err
 }
 
-func (d *dbQueue) Start() {
-	d.resetStuckJobs()
+func (d *dbQueue) Remove(jobID string) error {
+	return d.service.Remove(d.jobType, jobID)
+}
+
+func (d *dbQueue) Status(runTime time.Duration) (Status, error) {
+	status := Status{Concurrency: d.concurrent}
+	running, err := d.service.List(d.jobType, models.StatusStarted)
+	if err != nil {
+		return status, err
+	}
+	queued, err := d.service.Ranked(d.jobType)
+	if err != nil {
+		return status, err
+	}
 
+	dispatched := make([]time.Time, len(running))
+	for i, entry := range running {
+		dispatched[i] = entry.DispatchedAt
+	}
+
+	status.Queued = make([]models.QueuePosition, len(queued))
+	for i, entry := range queued {
+		status.Queued[i] = models.QueuePosition{
+			TypeID:   entry.TypeID,
+			UserID:   entry.UserID,
+			Position: i + 1,
+		}
+	}
+	status.Estimate(time.Now(), dispatched, runTime)
+	return status, nil
+}
+
+func (d *dbQueue) Start() {
 	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
 
-loop:
+	var notified <-chan struct{}
+	if d.notified != nil {
+		notified = d.notified.C
+		defer d.notified.Close()
+	}
+
+	// the ticker is only a safety net for missed notifications, and
+	// to pick up jobs lost by replicas which stopped sending heartbeats
+	d.resetStuckJobs()
+	d.fill()
 	for {
 		select {
 		case <-d.halt:
-			break loop
+			return
 		case <-ticker.C:
-			dispatched, err := d.service.Count(d.jobType, models.StatusStarted)
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-			toRun := d.concurrent - dispatched
-			if toRun > 0 {
-				jobs, err := d.service.Fetch(d.jobType, toRun)
-				if err != nil {
-					log.Error(err)
-					continue
-				}
-
-				for _, jobID := range jobs {
-					go d.dispatch(jobID)
-				}
-			}
+			d.resetStuckJobs()
+		case <-d.wake:
+		case <-notified:
 		}
+		d.fill()
+	}
+}
+
+// fill dispatches as many jobs as there are free slots.
+func (d *dbQueue) fill() {
+	jobs, err := d.service.Fetch(d.jobType, d.concurrent, d.maxPerUser)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	for _, entry := range jobs {
+		go d.dispatch(entry)
 	}
 }
 
@@ -79,37 +179,63 @@ func (d *dbQueue) Halt() {
 	close(d.halt)
 }
 
+// resetStuckJobs re-queues jobs whose replica hasn't sent a heartbeat for
+// them in so long that it has presumably died.
 func (d *dbQueue) resetStuckJobs() {
-	// pick jobs in limbo and re-queue them.
-	// use sync.Once to ensure that this can only be
-	// executed once.
-	d.once.Do(func() {
-		stuckJobs, err := d.service.FetchWithStatus(d.jobType, models.StatusStarted)
+	if d.stuckAfter <= 0 {
+		return
+	}
+	stuckJobs, err := d.service.FetchHeartbeatBefore(d.jobType, time.Now().Add(-d.stuckAfter))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, sj := range stuckJobs {
+		err := d.service.Update(d.jobType, sj, models.StatusQueued)
 		if err != nil {
 			log.Println(err)
-			return
-		}
-		for _, sj := range stuckJobs {
-			err := d.service.Update(d.jobType, sj, models.StatusQueued)
-			if err != nil {
-				log.Println(err)
-			}
 		}
-	})
+	}
 }
 
-func (d *dbQueue) dispatch(jobID string) {
-	// run job
-	err := d.service.Update(d.jobType, jobID, models.StatusStarted)
-	if err != nil {
-		log.Println(err)
-		return
-	}
+// dispatch runs a job Fetch has claimed, sending heartbeats for it until
+// it finishes, however long it runs.
+func (d *dbQueue) dispatch(entry models.QueueEntry) {
+	done := make(chan struct{})
+	go d.sendHeartbeats(entry.TypeID, done)
 
-	d.runner.Run(Job{ID: jobID})
+	d.runner.Run(Job{
+		ID:     entry.TypeID,
+		Weight: entry.Weight,
+		User:   entry.User,
+	})
+	close(done)
 
-	err = d.service.Update(d.jobType, jobID, models.StatusCompleted)
+	err := d.service.Update(d.jobType, entry.TypeID, models.StatusCompleted)
 	if err != nil {
 		log.Println(err)
 	}
+	d.signal()
+}
+
+// sendHeartbeats reports the job with jobID is still running, until done
+// is closed.
+func (d *dbQueue) sendHeartbeats(jobID string, done <-chan struct{}) {
+	interval := d.heartbeat
+	if interval <= 0 {
+		interval = defaultHeartbeat
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := d.service.Heartbeat(d.jobType, jobID)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
 }
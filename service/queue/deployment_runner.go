@@ -11,9 +11,16 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// defaultDeploymentPollInterval is how often DeploymentRunner checks if a
+// deployment has finished.
+const defaultDeploymentPollInterval = 30 * time.Second
+
 // DeploymentRunner is queue job runner implementation for deployments.
+// Run starts a deployment's instance, and waits for it to finish, so the
+// deployment holds its slot in the queue while it runs.
 type DeploymentRunner struct {
 	Hostname     string
+	Scheme       string // of callback URLs, https if empty
 	Service      deployment.Service
 	DB           *gorm.DB
 	pollInterval time.Duration
@@ -76,7 +83,11 @@ func (d DeploymentRunner) Run(j Job) {
 		return
 	}
 
-	callbackURL := fmt.Sprintf("https://%s/deployments/%s/events?token=%s", d.Hostname, deployment.ID, deployment.Token)
+	scheme := d.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	callbackURL := fmt.Sprintf("%s://%s/deployments/%s/events?token=%s", scheme, d.Hostname, deployment.ID, deployment.Token)
 
 	instanceID, err := d.Service.RunDeployment(context.Background(), deployment, callbackURL)
 	if err != nil {
@@ -84,18 +95,49 @@ func (d DeploymentRunner) Run(j Job) {
 		return
 	}
 
-	err = d.DB.Model(&deployment).Update("InstanceID", instanceID).Error
+	err = deploymentsDS.SetInstance(deployment, instanceID)
 	if err != nil {
 		log.Error(err)
 		return
 	}
 
 	newEvent := models.DeploymentEvent{Timestamp: time.Now(), Status: models.StatusQueued}
-	err = d.DB.Model(&deployment).Association("Events").Append(newEvent).Error
+	err = deploymentsDS.AddEvent(deployment, newEvent)
 	if err != nil {
 		log.Error(err)
 		return
 	}
+
+	err = d.wait(deployment)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// wait blocks until the deployment has finished, going by its events, so
+// it holds its slot in the queue, and counts towards its user's limit,
+// while its instance runs.
+func (d DeploymentRunner) wait(deployment models.Deployment) error {
+	interval := d.pollInterval
+	if interval <= 0 {
+		interval = defaultDeploymentPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var current models.Deployment
+		err := d.DB.Preload("Events", func(db *gorm.DB) *gorm.DB {
+			return db.Order("timestamp")
+		}).First(&current, "id = ?", deployment.ID).Error
+		if err != nil {
+			return err
+		}
+		if current.HasFinished() {
+			return nil
+		}
+	}
+	return nil
 }
 
 // Stop satisifies queue.JobRunner interface.
@@ -36,6 +36,7 @@ func startDeploymentQueue() (Queue, []Job) {
 		concurrent:   2,
 		service:      QueueService{db: db},
 		pollInterval: 10 * time.Millisecond,
+		wake:         make(chan struct{}, 1),
 		halt:         make(chan struct{}),
 	}
 
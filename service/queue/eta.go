@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ReconfigureIO/platform/models"
+)
+
+// Status is a snapshot of a queue.
+type Status struct {
+	// Dispatched is the number of jobs running.
+	Dispatched int
+	// Concurrency is the number of jobs the queue runs at once.
+	Concurrency int
+	// Queued are the jobs waiting, in the order they're expected to be
+	// dispatched.
+	Queued []models.QueuePosition
+}
+
+// Position returns where a job is in the queue, if it's waiting.
+func (s Status) Position(jobID string) (models.QueuePosition, bool) {
+	for _, p := range s.Queued {
+		if p.TypeID == jobID {
+			return p, true
+		}
+	}
+	return models.QueuePosition{}, false
+}
+
+// Estimate estimates when the queued jobs will start, given when the jobs
+// taking up the queue's slots started, if every job runs for runTime.
+func (s *Status) Estimate(now time.Time, running []time.Time, runTime time.Duration) {
+	starts := EstimateStarts(now, running, s.Concurrency, len(s.Queued), runTime)
+	s.Dispatched = len(running)
+	for i := range s.Queued {
+		s.Queued[i].Dispatched = len(running)
+		s.Queued[i].EstimatedStart = starts[i]
+	}
+}
+
+// EstimateStarts estimates when each of the next n queued jobs will start,
+// given when the running jobs were dispatched, if every job runs for
+// runTime. Jobs which have run for longer than runTime are assumed to be
+// about to finish.
+func EstimateStarts(now time.Time, dispatched []time.Time, concurrency int, n int, runTime time.Duration) []time.Time {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// free holds when each slot is next free
+	free := make([]time.Time, 0, len(dispatched)+concurrency)
+	for _, t := range dispatched {
+		end := t.Add(runTime)
+		if end.Before(now) {
+			end = now
+		}
+		free = append(free, end)
+	}
+	for len(free) < concurrency {
+		free = append(free, now)
+	}
+	sortTimes(free)
+	// with more jobs running than slots, the first to finish free nothing
+	free = free[len(free)-concurrency:]
+
+	starts := make([]time.Time, n)
+	for i := range starts {
+		starts[i] = free[0]
+		free[0] = free[0].Add(runTime)
+		sortTimes(free)
+	}
+	return starts
+}
+
+func sortTimes(times []time.Time) {
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+}
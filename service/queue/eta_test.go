@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ReconfigureIO/platform/models"
+)
+
+func TestEstimateStarts(t *testing.T) {
+	now := time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)
+	hour := time.Hour
+
+	for _, test := range []struct {
+		name        string
+		dispatched  []time.Time
+		concurrency int
+		expected    []time.Time
+	}{
+		{
+			name:        "free slots",
+			concurrency: 2,
+			expected:    []time.Time{now, now, now.Add(hour)},
+		},
+		{
+			name:        "busy slots",
+			dispatched:  []time.Time{now.Add(-30 * time.Minute), now.Add(-2 * hour)},
+			concurrency: 2,
+			expected:    []time.Time{now, now.Add(30 * time.Minute), now.Add(hour)},
+		},
+		{
+			name:        "more running than slots",
+			dispatched:  []time.Time{now, now.Add(-30 * time.Minute)},
+			concurrency: 1,
+			expected:    []time.Time{now.Add(hour), now.Add(2 * hour), now.Add(3 * hour)},
+		},
+	} {
+		starts := EstimateStarts(now, test.dispatched, test.concurrency, 3, hour)
+		if len(starts) != len(test.expected) {
+			t.Fatalf("%s: expected %d starts, got %d", test.name, len(test.expected), len(starts))
+		}
+		for i := range starts {
+			if !starts[i].Equal(test.expected[i]) {
+				t.Errorf("%s: expected job %d to start at %s, got %s", test.name, i, test.expected[i], starts[i])
+			}
+		}
+	}
+}
+
+func TestStatusEstimate(t *testing.T) {
+	now := time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)
+	status := Status{
+		Concurrency: 1,
+		Queued:      []models.QueuePosition{{TypeID: "1", Position: 1}, {TypeID: "2", Position: 2}},
+	}
+
+	status.Estimate(now, []time.Time{now.Add(-30 * time.Minute)}, time.Hour)
+
+	if status.Dispatched != 1 {
+		t.Errorf("Expected 1 job dispatched, got %d", status.Dispatched)
+	}
+	expected := []time.Time{now.Add(30 * time.Minute), now.Add(90 * time.Minute)}
+	for i, p := range status.Queued {
+		if p.Dispatched != 1 || !p.EstimatedStart.Equal(expected[i]) {
+			t.Errorf("Expected %s to start at %s behind 1 job, got %+v", p.TypeID, expected[i], p)
+		}
+	}
+}
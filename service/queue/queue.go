@@ -1,9 +1,19 @@
 package queue
 
 import (
+	"time"
+
 	"github.com/ReconfigureIO/platform/models"
 )
 
+// Job types, each of which has its own queue.
+const (
+	TypeDeployment = "deployment"
+	TypeBuild      = "build"
+	TypeSimulation = "simulation"
+	TypeGraph      = "graph"
+)
+
 // Queue is a job queue.
 type Queue interface {
 	// Push adds an entry to the queue.
@@ -20,6 +30,13 @@ type Queue interface {
 	// CountUserJobsInstatus counts the amount of user jobs
 	// in a status.
 	CountUserJobsInStatus(user models.User, status string) (int, error)
+	// Remove takes a job out of the queue if it is yet to
+	// be dispatched to the job runner.
+	Remove(jobID string) error
+	// Status returns the state of the queue, estimating
+	// when queued jobs will start if each job runs for
+	// runTime.
+	Status(runTime time.Duration) (Status, error)
 }
 
 // JobRunner manage jobs in the queue.
@@ -12,12 +12,16 @@ type QueueService struct {
 	db *gorm.DB
 }
 
+// NewQueueService returns a QueueService using db.
+func NewQueueService(db *gorm.DB) QueueService {
+	return QueueService{db: db}
+}
+
 // Push pushes a job into the queue.
 func (q *QueueService) Push(jobType string, job Job) error {
 	entry := models.QueueEntry{
 		Type:      jobType,
 		TypeID:    job.ID,
-		User:      job.User,
 		UserID:    job.User.ID,
 		Weight:    job.Weight,
 		Status:    models.StatusQueued,
@@ -26,11 +30,30 @@ func (q *QueueService) Push(jobType string, job Job) error {
 	return q.db.Create(&entry).Error
 }
 
-// Update updates a job on the queue.
+// Update updates a job on the queue, noting when it's dispatched.
 func (q *QueueService) Update(jobType string, jobID string, status string) error {
+	updates := map[string]interface{}{"status": status}
+	if status == models.StatusStarted {
+		now := time.Now()
+		updates["dispatched_at"] = now
+		updates["heartbeat_at"] = now
+	}
 	return q.db.Model(&models.QueueEntry{}).
 		Where("type = ? AND type_id = ?", jobType, jobID).
-		Update("status", status).Error
+		Updates(updates).Error
+}
+
+// Heartbeat notes a dispatched job is still running.
+func (q *QueueService) Heartbeat(jobType string, jobID string) error {
+	return q.db.Model(&models.QueueEntry{}).
+		Where("type = ? AND type_id = ? AND status = ?", jobType, jobID, models.StatusStarted).
+		Update("heartbeat_at", time.Now()).Error
+}
+
+// Remove deletes a job from the queue if it has not been dispatched.
+func (q *QueueService) Remove(jobType string, jobID string) error {
+	return q.db.Where("type = ? AND type_id = ? AND status = ?", jobType, jobID, models.StatusQueued).
+		Delete(&models.QueueEntry{}).Error
 }
 
 // Count counts the amount of jobs with status.
@@ -53,18 +76,109 @@ func (q *QueueService) CountUserJobsInStatus(jobType string, user models.User, s
 	return count, err
 }
 
-// Fetch fetches jobs by priority in the queue.
-func (q *QueueService) Fetch(jobType string, limit int) ([]string, error) {
+// sqlRankQueued ranks each user's queued jobs by priority, counting the
+// jobs they already have running, so the queue takes turns between users
+// rather than draining the highest weighted user first.
+const sqlRankQueued = `
+SELECT q.*,
+    row_number() OVER (PARTITION BY q.user_id ORDER BY q.weight DESC, q.created_at) + (
+        SELECT count(*)
+        FROM queue_entries s
+        WHERE s.type = q.type AND s.user_id = q.user_id AND s.status = ?
+    ) AS user_rank
+FROM queued q
+`
+
+// sqlFetchFairShare locks the queued jobs no other replica is fetching,
+// which can't be done alongside ranking them.
+const sqlFetchFairShare = `
+WITH queued AS (
+    SELECT *
+    FROM queue_entries
+    WHERE status = ? AND type = ?
+    FOR UPDATE SKIP LOCKED
+)
+SELECT type_id
+FROM (` + sqlRankQueued + `) ranked
+WHERE ? = 0 OR user_rank <= ?
+ORDER BY user_rank, weight DESC, created_at
+LIMIT ?
+`
+
+const sqlQueueOrder = `
+WITH queued AS (
+    SELECT *
+    FROM queue_entries
+    WHERE status = ? AND type = ?
+)
+SELECT *
+FROM (` + sqlRankQueued + `) ranked
+ORDER BY user_rank, weight DESC, created_at
+`
+
+// sqlLockJobType serialises fetching jobs of a type, so each replica
+// counts the jobs the others have claimed before claiming its own. The
+// lock is released when the transaction ends.
+const sqlLockJobType = `SELECT pg_advisory_xact_lock(hashtext('queue_entries:' || ?))`
+
+// Fetch claims jobs by priority in the queue, until concurrent jobs are
+// running, sharing them fairly between users, and leaving out jobs of
+// users with maxPerUser jobs running unless it is 0. Claimed jobs are
+// marked as dispatched. Replicas fetch jobs of a type one at a time, so
+// no job is claimed twice, and neither limit is exceeded between them.
+func (q *QueueService) Fetch(jobType string, concurrent int, maxPerUser int) ([]models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	tx := q.db.Begin()
+	err := tx.Exec(sqlLockJobType, jobType).Error
 	var jobs []string
-	rows, err := q.db.Model(&models.QueueEntry{}).
-		Select("type_id").
-		Where("status = ? AND type = ?", models.StatusQueued, jobType).
-		Order("weight desc, created_at").
-		Limit(limit).
-		Rows()
+	if err == nil {
+		jobs, err = fetchFairShare(tx, jobType, concurrent, maxPerUser)
+	}
+	if err == nil && len(jobs) > 0 {
+		now := time.Now()
+		err = tx.Model(&models.QueueEntry{}).
+			Where("type = ? AND type_id in (?)", jobType, jobs).
+			Updates(map[string]interface{}{
+				"status":        models.StatusStarted,
+				"dispatched_at": now,
+				"heartbeat_at":  now,
+			}).Error
+	}
+	if err == nil && len(jobs) > 0 {
+		err = tx.Preload("User").
+			Where("type = ? AND type_id in (?)", jobType, jobs).
+			Order("weight desc, created_at").
+			Find(&entries).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return entries, tx.Commit().Error
+}
+
+func fetchFairShare(tx *gorm.DB, jobType string, concurrent int, maxPerUser int) ([]string, error) {
+	var jobs []string
+	var running int
+	err := tx.Model(&models.QueueEntry{}).
+		Where("status = ? AND type = ?", models.StatusStarted, jobType).
+		Count(&running).Error
 	if err != nil {
 		return jobs, err
 	}
+	limit := concurrent - running
+	if limit <= 0 {
+		return jobs, nil
+	}
+
+	rows, err := tx.Raw(sqlFetchFairShare,
+		models.StatusQueued, jobType, models.StatusStarted,
+		maxPerUser, maxPerUser, limit,
+	).Rows()
+	if err != nil {
+		return jobs, err
+	}
+	defer rows.Close()
 	for rows.Next() {
 		var job string
 		err := rows.Scan(&job)
@@ -73,7 +187,37 @@ func (q *QueueService) Fetch(jobType string, limit int) ([]string, error) {
 		}
 		jobs = append(jobs, job)
 	}
-	return jobs, nil
+	return jobs, rows.Err()
+}
+
+// Ranked lists the queued jobs in the order they'll be dispatched if no
+// user reaches their limit of running jobs.
+func (q *QueueService) Ranked(jobType string) ([]models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := q.db.Raw(sqlQueueOrder, models.StatusQueued, jobType, models.StatusStarted).
+		Scan(&entries).Error
+	return entries, err
+}
+
+// List lists the jobs with status in the order they'll be dispatched,
+// ignoring fair share.
+func (q *QueueService) List(jobType string, status string) ([]models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := q.db.Where("status = ? AND type = ?", status, jobType).
+		Order("weight desc, created_at").
+		Find(&entries).Error
+	return entries, err
+}
+
+// SetWeight reprioritises a job which is yet to be dispatched.
+func (q *QueueService) SetWeight(entryID string, weight int) (models.QueueEntry, error) {
+	var entry models.QueueEntry
+	err := q.db.First(&entry, "id = ? AND status = ?", entryID, models.StatusQueued).Error
+	if err != nil {
+		return entry, err
+	}
+	err = q.db.Model(&entry).Update("weight", weight).Error
+	return entry, err
 }
 
 // Fetch fetches jobs with status.
@@ -97,3 +241,13 @@ func (q *QueueService) FetchWithStatus(jobType string, status string) ([]string,
 	}
 	return jobs, nil
 }
+
+// FetchHeartbeatBefore fetches running jobs whose last heartbeat was
+// before t.
+func (q *QueueService) FetchHeartbeatBefore(jobType string, t time.Time) ([]string, error) {
+	var jobs []string
+	err := q.db.Model(&models.QueueEntry{}).
+		Where("status = ? AND type = ? AND GREATEST(heartbeat_at, dispatched_at) < ?", models.StatusStarted, jobType, t).
+		Pluck("type_id", &jobs).Error
+	return jobs, err
+}
@@ -10,7 +10,7 @@ import (
 	"testing"
 	"time"
 
-	_ "github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/models"
 	"github.com/jinzhu/gorm"
 )
 
@@ -39,6 +39,7 @@ func TestDBQueue(t *testing.T) {
 		concurrent:   2,
 		service:      QueueService{db: connectDB()},
 		pollInterval: 10 * time.Millisecond,
+		wake:         make(chan struct{}, 1),
 		halt:         make(chan struct{}),
 	}
 
@@ -62,6 +63,35 @@ func TestDBQueue(t *testing.T) {
 	}
 }
 
+func TestDBQueueWakesOnPush(t *testing.T) {
+	runner := &fakeRunner{}
+	var queue = &dbQueue{
+		jobType:      "wake-on-push",
+		runner:       runner,
+		concurrent:   1,
+		service:      QueueService{db: connectDB()},
+		pollInterval: time.Hour,
+		wake:         make(chan struct{}, 1),
+		halt:         make(chan struct{}),
+	}
+	go queue.Start()
+	defer queue.Halt()
+
+	queue.Push(Job{ID: "wake-1", Weight: 1})
+	queue.Push(Job{ID: "wake-2", Weight: 1})
+
+	// the second job is dispatched when the first finishes, long before
+	// the queue polls
+	for i := 0; i < 50 && atomic.LoadUint64(&runner.nDispatched) < 2; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	for _, id := range []string{"wake-1", "wake-2"} {
+		if _, ok := runner.dispatched.Load(id); !ok {
+			t.Errorf("Job %s not dispatched", id)
+		}
+	}
+}
+
 type fakeRunner struct {
 	dispatched  sync.Map
 	nDispatched uint64
@@ -77,3 +107,154 @@ func (f *fakeRunner) Run(job Job) {
 func (f fakeRunner) Stop(job Job) {
 	log.Println("stopping", job.ID)
 }
+
+func TestDBQueueRemove(t *testing.T) {
+	service := QueueService{db: connectDB()}
+	queued := Job{ID: "remove-queued", Weight: 1}
+	started := Job{ID: "remove-started", Weight: 1}
+
+	for _, job := range []Job{queued, started} {
+		if err := service.Push("deployment", job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := service.Update("deployment", started.ID, models.StatusStarted); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, job := range []Job{queued, started} {
+		if err := service.Remove("deployment", job.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := service.FetchWithStatus("deployment", models.StatusQueued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range jobs {
+		if job == queued.ID {
+			t.Errorf("Job %s should have been removed from the queue", job)
+		}
+	}
+
+	jobs, err = service.FetchWithStatus("deployment", models.StatusStarted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, job := range jobs {
+		found = found || job == started.ID
+	}
+	if !found {
+		t.Errorf("Dispatched job %s should not be removed from the queue", started.ID)
+	}
+}
+
+func TestDBQueueFetchFairShare(t *testing.T) {
+	service := QueueService{db: connectDB()}
+	busy := models.User{ID: "fair-share-busy"}
+	idle := models.User{ID: "fair-share-idle"}
+	entries := []Job{
+		{ID: "fair-share-running", Weight: 5, User: busy},
+		{ID: "fair-share-busy-1", Weight: 5, User: busy},
+		{ID: "fair-share-busy-2", Weight: 5, User: busy},
+		{ID: "fair-share-idle-1", Weight: 1, User: idle},
+	}
+	for _, job := range entries {
+		if err := service.Push("fair-share", job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := service.Update("fair-share", "fair-share-running", models.StatusStarted); err != nil {
+		t.Fatal(err)
+	}
+
+	ranked, err := service.Ranked("fair-share")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the idle user hasn't had a turn yet, so goes before the busy user
+	// despite their lower weight
+	order := []string{"fair-share-idle-1", "fair-share-busy-1", "fair-share-busy-2"}
+	if len(ranked) != len(order) {
+		t.Fatalf("Expected %d queued jobs, got %d", len(order), len(ranked))
+	}
+	for i, entry := range ranked {
+		if entry.TypeID != order[i] {
+			t.Errorf("Expected %s at position %d, got %s", order[i], i+1, entry.TypeID)
+		}
+	}
+
+	jobs, err := service.Fetch("fair-share", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].TypeID != "fair-share-idle-1" {
+		t.Errorf("Expected only the idle user's job, got %v", jobs)
+	}
+
+	// the running jobs count towards the concurrency limit
+	jobs, err = service.Fetch("fair-share", 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("Expected no jobs with all slots taken, got %v", jobs)
+	}
+
+	jobs, err = service.Fetch("fair-share", 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].TypeID != "fair-share-busy-1" {
+		t.Errorf("Expected the busy user's next job, got %v", jobs)
+	}
+
+	// fetched jobs are claimed
+	ids, err := service.FetchWithStatus("fair-share", models.StatusQueued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "fair-share-busy-2" {
+		t.Errorf("Expected only fair-share-busy-2 to be queued, got %v", ids)
+	}
+}
+
+func TestDBQueueResetsJobsWithoutHeartbeats(t *testing.T) {
+	db := connectDB()
+	queue := &dbQueue{
+		jobType:    "heartbeat",
+		service:    QueueService{db: db},
+		stuckAfter: time.Minute,
+	}
+	for _, id := range []string{"heartbeat-live", "heartbeat-lost"} {
+		if err := queue.service.Push("heartbeat", Job{ID: id, Weight: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if err := queue.service.Update("heartbeat", id, models.StatusStarted); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// both jobs were dispatched long ago, but only one is still running
+	longAgo := time.Now().Add(-24 * time.Hour)
+	err := db.Model(&models.QueueEntry{}).
+		Where("type = ?", "heartbeat").
+		Updates(map[string]interface{}{"dispatched_at": longAgo, "heartbeat_at": longAgo}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.service.Heartbeat("heartbeat", "heartbeat-live"); err != nil {
+		t.Fatal(err)
+	}
+
+	queue.resetStuckJobs()
+
+	ids, err := queue.service.FetchWithStatus("heartbeat", models.StatusQueued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "heartbeat-lost" {
+		t.Errorf("Expected only heartbeat-lost to be queued again, got %v", ids)
+	}
+}
@@ -0,0 +1,164 @@
+// Package retention removes old build, simulation and graph objects from
+// storage.
+package retention
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/storage"
+)
+
+// Config is the retention policy.
+type Config struct {
+	// MaxAgeDays is how long objects are kept for.
+	MaxAgeDays int `env:"RECO_RETENTION_MAX_AGE_DAYS" envDefault:"90"`
+	// KeepLast is how many of the most recent builds, simulations and
+	// graphs of each project are kept regardless of age.
+	KeepLast int `env:"RECO_RETENTION_KEEP_LAST" envDefault:"10"`
+	// DryRun reports what would be removed without removing it.
+	DryRun bool `env:"RECO_RETENTION_DRY_RUN" envDefault:"true"`
+}
+
+// kind is a type of job whose objects are stored under prefix/<id>/.
+type kind struct {
+	prefix string
+	table  string
+}
+
+var kinds = []kind{
+	{prefix: "builds/", table: "builds"},
+	{prefix: "simulation/", table: "simulations"},
+	{prefix: "graphs/", table: "graphs"},
+}
+
+// Report is what a collection removed, or would have in a dry run.
+type Report struct {
+	DryRun bool
+	Keys   []string
+	Bytes  int64
+}
+
+// Collector applies a retention policy to storage.
+type Collector struct {
+	Storage storage.Service
+	Repo    models.RetentionRepo
+	Config  Config
+}
+
+// item is the objects of one build, simulation or graph.
+type item struct {
+	id           string
+	project      string
+	lastModified time.Time
+	objects      []storage.Object
+}
+
+// Collect removes objects which the policy no longer retains.
+func (c *Collector) Collect(now time.Time) (Report, error) {
+	report := Report{DryRun: c.Config.DryRun}
+
+	deployed, err := c.Repo.ActivelyDeployedBuilds()
+	if err != nil {
+		return report, err
+	}
+	protected := make(map[string]bool)
+	for _, id := range deployed {
+		protected["builds/"+id] = true
+	}
+
+	cutoff := now.AddDate(0, 0, -c.Config.MaxAgeDays)
+	for _, k := range kinds {
+		items, err := c.items(k)
+		if err != nil {
+			return report, err
+		}
+		for _, it := range c.expired(items, cutoff) {
+			if protected[k.prefix+it.id] {
+				continue
+			}
+			for _, o := range it.objects {
+				err = c.remove(o)
+				if err != nil {
+					return report, err
+				}
+				report.Keys = append(report.Keys, o.Key)
+				report.Bytes += o.Size
+			}
+		}
+	}
+	return report, nil
+}
+
+// items groups the stored objects of a kind by ID, with their projects.
+func (c *Collector) items(k kind) ([]*item, error) {
+	objects, err := c.Storage.List(k.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*item)
+	ids := []string{}
+	for _, o := range objects {
+		id := strings.SplitN(strings.TrimPrefix(o.Key, k.prefix), "/", 2)[0]
+		it, ok := byID[id]
+		if !ok {
+			it = &item{id: id}
+			byID[id] = it
+			ids = append(ids, id)
+		}
+		it.objects = append(it.objects, o)
+		if o.LastModified.After(it.lastModified) {
+			it.lastModified = o.LastModified
+		}
+	}
+
+	projects, err := c.Repo.ProjectIDs(k.table, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*item, 0, len(ids))
+	for _, id := range ids {
+		it := byID[id]
+		it.project = projects[id]
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// expired returns the items last modified before cutoff which aren't
+// among the KeepLast most recent of their project. Objects which no longer
+// belong to a project only have to be old.
+func (c *Collector) expired(items []*item, cutoff time.Time) []*item {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].lastModified.After(items[j].lastModified)
+	})
+
+	kept := make(map[string]int)
+	var old []*item
+	for _, it := range items {
+		if it.project != "" && kept[it.project] < c.Config.KeepLast {
+			kept[it.project]++
+			continue
+		}
+		if it.lastModified.Before(cutoff) {
+			old = append(old, it)
+		}
+	}
+	return old
+}
+
+func (c *Collector) remove(o storage.Object) error {
+	fields := log.Fields{"key": o.Key, "size": o.Size, "last_modified": o.LastModified}
+	if c.Config.DryRun {
+		log.WithFields(fields).Info("retention: would remove")
+		return nil
+	}
+	log.WithFields(fields).Info("retention: removing")
+	return c.Storage.Delete(o.Key)
+}
@@ -0,0 +1,100 @@
+package retention
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/ReconfigureIO/platform/models"
+	"github.com/ReconfigureIO/platform/service/storage"
+)
+
+func TestCollect(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -100)
+	recent := now.AddDate(0, 0, -1)
+
+	builds := []storage.Object{
+		// newest of project p1, kept
+		{Key: "builds/b1/build.tar.gz", Size: 1, LastModified: old.Add(time.Hour)},
+		// old, removed
+		{Key: "builds/b2/build.tar.gz", Size: 2, LastModified: old},
+		{Key: "builds/b2/artifacts.zip", Size: 3, LastModified: old},
+		// old, but deployed
+		{Key: "builds/b3/artifacts.zip", Size: 4, LastModified: old.Add(-time.Hour)},
+		// recent
+		{Key: "builds/b4/build.tar.gz", Size: 5, LastModified: recent},
+		// old and orphaned
+		{Key: "builds/b5/build.tar.gz", Size: 6, LastModified: old},
+	}
+
+	s := storage.NewMockService(mockCtrl)
+	s.EXPECT().List("builds/").Return(builds, nil)
+	s.EXPECT().List("simulation/").Return(nil, nil)
+	s.EXPECT().List("graphs/").Return(nil, nil)
+
+	repo := models.NewMockRetentionRepo(mockCtrl)
+	repo.EXPECT().ActivelyDeployedBuilds().Return([]string{"b3"}, nil)
+	repo.EXPECT().ProjectIDs("builds", gomock.Any()).Return(map[string]string{
+		"b1": "p1", "b2": "p1", "b3": "p1", "b4": "p2",
+	}, nil)
+	repo.EXPECT().ProjectIDs("simulations", gomock.Any()).Return(map[string]string{}, nil)
+	repo.EXPECT().ProjectIDs("graphs", gomock.Any()).Return(map[string]string{}, nil)
+
+	expected := []string{"builds/b2/artifacts.zip", "builds/b2/build.tar.gz", "builds/b5/build.tar.gz"}
+	for _, key := range expected {
+		s.EXPECT().Delete(key).Return(nil)
+	}
+
+	c := Collector{
+		Storage: s,
+		Repo:    repo,
+		Config:  Config{MaxAgeDays: 90, KeepLast: 1},
+	}
+	report, err := c.Collect(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(report.Keys)
+	if !reflect.DeepEqual(report.Keys, expected) {
+		t.Errorf("Expected to remove %v, removed %v", expected, report.Keys)
+	}
+	if report.Bytes != 11 {
+		t.Errorf("Expected to remove 11 bytes, removed %d", report.Bytes)
+	}
+}
+
+func TestCollectDryRun(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	s := storage.NewMockService(mockCtrl)
+	s.EXPECT().List(gomock.Any()).Return([]storage.Object{
+		{Key: "graphs/g1/graph.pdf.gz", LastModified: time.Unix(0, 0)},
+	}, nil).Times(3)
+
+	repo := models.NewMockRetentionRepo(mockCtrl)
+	repo.EXPECT().ActivelyDeployedBuilds().Return(nil, nil)
+	repo.EXPECT().ProjectIDs(gomock.Any(), gomock.Any()).Return(map[string]string{}, nil).Times(3)
+
+	// no Delete is expected
+	c := Collector{
+		Storage: s,
+		Repo:    repo,
+		Config:  Config{MaxAgeDays: 90, DryRun: true},
+	}
+	report, err := c.Collect(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || len(report.Keys) != 3 {
+		t.Errorf("Expected a dry run reporting 3 keys, got %+v", report)
+	}
+}
@@ -1,22 +1,44 @@
 package localfile
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ReconfigureIO/platform/service/storage"
 )
 
+// ErrInvalidSignature is returned for signed URLs which are wrong or expired.
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
 // Service represents the directory which localfile.Service uses for storage.
-type Service string
+// Pre-signed URLs point at BaseURL, where Handler should be served, and are
+// signed with Secret.
+type Service struct {
+	Dir     string
+	BaseURL url.URL
+	Secret  []byte
+}
 
 // Upload writes the contents of `r`` to a file with the given key name.
 func (s Service) Upload(key string, r io.Reader) (s3url string, err error) {
-	err = os.MkdirAll(string(s), 0777)
+	path := filepath.Join(s.Dir, key)
+	err = os.MkdirAll(filepath.Dir(path), 0777)
 	if err != nil {
 		return "", err
 	}
 
-	fd, err := os.Create(filepath.Join(string(s), key))
+	fd, err := os.Create(path)
 	if err != nil {
 		return "", err
 	}
@@ -37,5 +59,122 @@ func (s Service) Upload(key string, r io.Reader) (s3url string, err error) {
 
 // Download returns a reader to the contents of the filename `key`.
 func (s Service) Download(key string) (io.ReadCloser, error) {
-	return os.Open(filepath.Join(string(s), key))
+	return os.Open(filepath.Join(s.Dir, key))
+}
+
+// List returns every file with a key starting with prefix.
+func (s Service) List(prefix string) ([]storage.Object, error) {
+	objects := []storage.Object{}
+	err := filepath.Walk(s.Dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil || info.IsDir() {
+			return err
+		}
+		key, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(key)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, storage.Object{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		}
+		return nil
+	})
+	return objects, err
+}
+
+// Delete removes the file with the given key name.
+func (s Service) Delete(key string) error {
+	err := os.Remove(filepath.Join(s.Dir, key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Copy copies the file with key src to key dst.
+func (s Service) Copy(src string, dst string) error {
+	r, err := s.Download(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = s.Upload(dst, r)
+	return err
+}
+
+// PresignPut returns a URL to upload key to with a PUT request to Handler.
+func (s Service) PresignPut(key string, expiry time.Duration) (string, error) {
+	return s.presign("PUT", key, time.Now().Add(expiry)), nil
+}
+
+// PresignGet returns a URL to download key from with Handler.
+func (s Service) PresignGet(key string, expiry time.Duration) (string, error) {
+	return s.presign("GET", key, time.Now().Add(expiry)), nil
+}
+
+func (s Service) presign(method string, key string, expires time.Time) string {
+	u := s.BaseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	u.RawQuery = url.Values{
+		"expires":   {exp},
+		"signature": {s.sign(method, key, exp)},
+	}.Encode()
+	return u.String()
+}
+
+func (s Service) sign(method string, key string, expires string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s", method, key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature made by PresignPut or PresignGet.
+func (s Service) Verify(method string, key string, expires string, signature string, now time.Time) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > exp {
+		return ErrInvalidSignature
+	}
+	expected := s.sign(method, key, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Handler serves the pre-signed URLs, with keys as the path. It should be
+// mounted at BaseURL with the path prefix stripped.
+func (s Service) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if key == "" || strings.Contains(key, "..") {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		err := s.Verify(r.Method, key, query.Get("expires"), query.Get("signature"), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			http.ServeFile(w, r, filepath.Join(s.Dir, key))
+		case "PUT":
+			_, err := s.Upload(key, r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
 }
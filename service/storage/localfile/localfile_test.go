@@ -0,0 +1,104 @@
+package localfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPresignedRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "localfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := Service{Dir: dir, Secret: []byte("secret")}
+	server := httptest.NewServer(http.StripPrefix("/storage", s.Handler()))
+	defer server.Close()
+
+	base, _ := url.Parse(server.URL + "/storage")
+	s.BaseURL = *base
+
+	putURL, _ := s.PresignPut("builds/1/build.tar.gz", time.Minute)
+	req, _ := http.NewRequest("PUT", putURL, bytes.NewBufferString("foo"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected upload to succeed, got %d", resp.StatusCode)
+	}
+
+	// a PUT URL can't be used to download
+	resp, err = http.Get(putURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 403 {
+		t.Fatalf("Expected GET with a PUT signature to be forbidden, got %d", resp.StatusCode)
+	}
+
+	getURL, _ := s.PresignGet("builds/1/build.tar.gz", time.Minute)
+	resp, err = http.Get(getURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "foo" {
+		t.Fatalf("Expected to download foo, got %s", body)
+	}
+}
+
+func TestVerifyExpired(t *testing.T) {
+	s := Service{Secret: []byte("secret")}
+	expires := "1000"
+	sig := s.sign("GET", "key", expires)
+	if err := s.Verify("GET", "key", expires, sig, time.Unix(999, 0)); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+	if err := s.Verify("GET", "key", expires, sig, time.Unix(1001, 0)); err != ErrInvalidSignature {
+		t.Errorf("Expected expired signature to be invalid, got %v", err)
+	}
+	if err := s.Verify("GET", "other", expires, sig, time.Unix(999, 0)); err != ErrInvalidSignature {
+		t.Errorf("Expected signature for another key to be invalid, got %v", err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "localfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := Service{Dir: dir}
+	if _, err := s.Upload("builds/1/build.tar.gz", bytes.NewBufferString("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Copy("builds/1/build.tar.gz", "builds/2/build.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := s.Download("builds/2/build.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(body) != "foo" {
+		t.Fatalf("Expected copy to contain foo, got %s", body)
+	}
+
+	if err := s.Copy("builds/3/build.tar.gz", "builds/4/build.tar.gz"); err == nil {
+		t.Errorf("Expected copying a missing file to fail")
+	}
+}
@@ -2,7 +2,10 @@ package s3
 
 import (
 	"io"
+	"net/url"
+	"time"
 
+	"github.com/ReconfigureIO/platform/service/storage"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
@@ -38,6 +41,62 @@ func (s *Service) Download(key string) (io.ReadCloser, error) {
 	return object.Body, err
 }
 
+// PresignPut returns a URL to upload key to, valid for expiry.
+func (s *Service) PresignPut(key string, expiry time.Duration) (string, error) {
+	req, _ := s.S3API.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
+}
+
+// PresignGet returns a URL to download key from, valid for expiry.
+func (s *Service) PresignGet(key string, expiry time.Duration) (string, error) {
+	req, _ := s.S3API.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
+}
+
+// List returns every object with a key starting with prefix.
+func (s *Service) List(prefix string) ([]storage.Object, error) {
+	objects := []storage.Object{}
+	err := s.S3API.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range page.Contents {
+			objects = append(objects, storage.Object{
+				Key:          aws.StringValue(o.Key),
+				Size:         aws.Int64Value(o.Size),
+				LastModified: aws.TimeValue(o.LastModified),
+			})
+		}
+		return true
+	})
+	return objects, err
+}
+
+// Delete removes key.
+func (s *Service) Delete(key string) error {
+	_, err := s.S3API.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// Copy copies src to dst within the bucket.
+func (s *Service) Copy(src string, dst string) error {
+	_, err := s.S3API.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(s.Bucket),
+		CopySource: aws.String(url.PathEscape(s.Bucket + "/" + src)),
+		Key:        aws.String(dst),
+	})
+	return err
+}
+
 func (s *Service) s3Url(key string) string {
 	return "s3://" + s.Bucket + "/" + key
 }
@@ -2,11 +2,32 @@ package storage
 
 //go:generate mockgen -source=storage.go -package=storage -destination=storage_mock.go
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 // A Service provides a content store.
 // It is implemented by service/storage/s3.Service.
 type Service interface {
 	Upload(key string, r io.Reader) (string, error)
 	Download(key string) (io.ReadCloser, error)
+	// PresignPut returns a URL to upload key to with a PUT request, valid
+	// for expiry.
+	PresignPut(key string, expiry time.Duration) (string, error)
+	// PresignGet returns a URL to download key from, valid for expiry.
+	PresignGet(key string, expiry time.Duration) (string, error)
+	// List returns every object with a key starting with prefix.
+	List(prefix string) ([]Object, error)
+	// Delete removes key, it is not an error if it doesn't exist.
+	Delete(key string) error
+	// Copy copies the object at src to dst, without downloading it.
+	Copy(src string, dst string) error
+}
+
+// Object describes a stored object.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
 }
@@ -1,12 +1,9 @@
 package stream
 
 import (
-	"bytes"
 	"context"
 	"io"
-	"time"
 
-	"github.com/ReconfigureIO/platform/service/aws"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,38 +17,3 @@ func StartWithContext(ctx context.Context, c *gin.Context, step func(ctx context
 		}
 	}
 }
-
-// Start starts a stream of cloudwatch log events, and stream the messages to
-// the client until it finishes.
-func Start(ctx context.Context, stream *aws.Stream, c *gin.Context, logGroup string) {
-	go func() {
-		err := stream.Run(ctx, logGroup)
-		if err != nil {
-			c.Error(err)
-		}
-	}()
-
-	StartWithContext(ctx, c, func(ctx context.Context, w io.Writer) bool {
-		ticker := time.NewTicker(10 * time.Second)
-		defer ticker.Stop()
-
-		select {
-		case <-ctx.Done():
-			return false
-		case log, ok := <-stream.Events:
-			if ok {
-				for _, e := range log.Events {
-					_, err := bytes.NewBufferString((*e.Message) + "\n").WriteTo(w)
-					if err != nil {
-						c.Error(err)
-						return false
-					}
-				}
-			}
-			return ok
-		case <-ticker.C:
-			bytes.NewBuffer([]byte{0}).WriteTo(w)
-			return true
-		}
-	})
-}
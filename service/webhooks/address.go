@@ -0,0 +1,118 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for webhooks at loopback, link-local,
+// private or otherwise internal addresses, such as the EC2 metadata
+// service, which we won't deliver to.
+var ErrForbiddenAddress = errors.New("webhook URL resolves to a loopback, link-local or private address")
+
+// forbiddenNets are the networks webhooks can't be delivered to.
+var forbiddenNets = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// allowedIP returns if webhooks may be delivered to ip. IPv4 addresses
+// mapped into IPv6 are checked as IPv4.
+func allowedIP(ip net.IP) bool {
+	for _, n := range forbiddenNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL returns ErrForbiddenAddress if any address the host of rawurl
+// resolves to is one webhooks can't be delivered to.
+func CheckURL(ctx context.Context, rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("webhook URL %q has no host", rawurl)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("couldn't resolve webhook host %s: %v", host, err)
+	}
+	for _, addr := range addrs {
+		if !allowedIP(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// checkDial refuses connections to forbidden addresses. It's called with
+// the address being dialed once the host has been resolved, so a host
+// whose DNS changes after CheckURL is still refused.
+func checkDial(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !allowedIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// NewClient returns a client for delivering webhooks, which won't connect
+// to forbidden addresses, including when following redirects. It doesn't
+// use a proxy, as that would hide the address being connected to.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkDial,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllowedIP(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::":      true,
+		"127.0.0.1":              false,
+		"::1":                    false,
+		"169.254.169.254":        false,
+		"10.1.2.3":               false,
+		"172.20.0.1":             false,
+		"192.168.1.1":            false,
+		"0.0.0.0":                false,
+		"fd00::1":                false,
+		"fe80::1":                false,
+		"::ffff:169.254.169.254": false,
+	}
+	for addr, expected := range cases {
+		if allowed := allowedIP(net.ParseIP(addr)); allowed != expected {
+			t.Errorf("Expected %s allowed to be %v, got %v", addr, expected, allowed)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for _, u := range []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://localhost:8080/hook",
+		"https://[::1]/hook",
+	} {
+		if err := CheckURL(context.Background(), u); err != ErrForbiddenAddress {
+			t.Errorf("Expected %s to be forbidden, got %v", u, err)
+		}
+	}
+	if err := CheckURL(context.Background(), "http://93.184.216.34/hook"); err != nil {
+		t.Errorf("Expected public address to be allowed, got %v", err)
+	}
+}
+
+func TestNewClientRefusesForbiddenAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the webhook not to be delivered")
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Post(server.URL, "application/json", nil)
+	if err == nil {
+		t.Fatal("Expected delivery to a loopback address to fail")
+	}
+}
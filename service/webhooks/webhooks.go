@@ -0,0 +1,144 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ReconfigureIO/platform/models"
+)
+
+const (
+	// SignatureHeader holds the HMAC-SHA256 of the body, keyed with the
+	// webhook's secret.
+	SignatureHeader = "X-Reco-Signature"
+	// EventHeader holds the type of the event being delivered.
+	EventHeader = "X-Reco-Event"
+	// DeliveryHeader holds the ID of the delivery, which is the same
+	// across retries.
+	DeliveryHeader = "X-Reco-Delivery"
+
+	defaultMaxAttempts = 8
+	defaultBackoff     = 30 * time.Second
+	maxBackoff         = 6 * time.Hour
+)
+
+// Sign returns the signature of body for a webhook with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliverer POSTs pending webhook deliveries, retrying failures with
+// exponential backoff.
+type Deliverer struct {
+	Repo   models.WebhookRepo
+	Client *http.Client
+	// MaxAttempts is how many times a delivery is tried before it is
+	// marked as failed.
+	MaxAttempts int
+	// Backoff is the wait before the first retry, it doubles every attempt.
+	Backoff time.Duration
+}
+
+// DeliverDue attempts up to limit deliveries which are due.
+func (d *Deliverer) DeliverDue(ctx context.Context, limit int) error {
+	deliveries, err := d.Repo.Due(time.Now(), limit)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		delivery = d.attempt(ctx, delivery)
+		err = d.Repo.RecordAttempt(delivery)
+		if err != nil {
+			log.WithError(err).
+				WithFields(log.Fields{"delivery_id": delivery.ID}).
+				Error("Couldn't record webhook delivery attempt")
+		}
+	}
+	return nil
+}
+
+// attempt POSTs a delivery, returning it updated with the outcome.
+func (d *Deliverer) attempt(ctx context.Context, delivery models.WebhookDelivery) models.WebhookDelivery {
+	delivery.Attempts++
+	delivery.ResponseCode = 0
+	delivery.Error = ""
+
+	code, err := d.post(ctx, delivery)
+	delivery.ResponseCode = code
+	now := time.Now()
+
+	if err == nil {
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		return delivery
+	}
+
+	delivery.Error = err.Error()
+	if delivery.Attempts >= d.maxAttempts() {
+		delivery.Status = models.DeliveryFailed
+		return delivery
+	}
+	delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	return delivery
+}
+
+func (d *Deliverer) post(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest("POST", delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(delivery.Webhook.Secret, body))
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Deliverer) maxAttempts() int {
+	if d.MaxAttempts > 0 {
+		return d.MaxAttempts
+	}
+	return defaultMaxAttempts
+}
+
+// backoff returns the wait before retrying after attempts failures.
+func (d *Deliverer) backoff(attempts int) time.Duration {
+	wait := d.Backoff
+	if wait <= 0 {
+		wait = defaultBackoff
+	}
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
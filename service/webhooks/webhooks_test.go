@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/ReconfigureIO/platform/models"
+)
+
+func TestDeliverDue(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var gotSignature, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(body)
+		gotSignature = r.Header.Get(SignatureHeader)
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	delivery := models.WebhookDelivery{
+		ID:        "delivery",
+		EventType: "build.completed",
+		Payload:   `{"type":"build.completed"}`,
+		Status:    models.DeliveryPending,
+		Webhook:   models.Webhook{URL: server.URL, Secret: "secret"},
+	}
+
+	repo := models.NewMockWebhookRepo(mockCtrl)
+	repo.EXPECT().Due(gomock.Any(), 10).Return([]models.WebhookDelivery{delivery}, nil)
+	repo.EXPECT().RecordAttempt(gomock.Any()).Do(func(d models.WebhookDelivery) {
+		if d.Status != models.DeliveryDelivered || d.Attempts != 1 || d.ResponseCode != 204 {
+			t.Errorf("Unexpected delivery outcome: %+v", d)
+		}
+	}).Return(nil)
+
+	d := Deliverer{Repo: repo}
+	err := d.DeliverDue(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gotBody != delivery.Payload {
+		t.Errorf("Expected body %s, got %s", delivery.Payload, gotBody)
+	}
+	if expected := Sign("secret", []byte(delivery.Payload)); gotSignature != expected {
+		t.Errorf("Expected signature %s, got %s", expected, gotSignature)
+	}
+}
+
+func TestAttemptRetriesThenFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+
+	d := Deliverer{MaxAttempts: 2, Backoff: time.Minute}
+	delivery := models.WebhookDelivery{
+		Status:  models.DeliveryPending,
+		Webhook: models.Webhook{URL: server.URL},
+	}
+
+	before := time.Now()
+	delivery = d.attempt(context.Background(), delivery)
+	if delivery.Status != models.DeliveryPending || delivery.ResponseCode != 500 {
+		t.Fatalf("Expected a pending retry, got %+v", delivery)
+	}
+	if delivery.NextAttemptAt.Before(before.Add(time.Minute)) {
+		t.Errorf("Expected retry to back off, next attempt at %s", delivery.NextAttemptAt)
+	}
+
+	delivery = d.attempt(context.Background(), delivery)
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 2 {
+		t.Fatalf("Expected delivery to fail, got %+v", delivery)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := Deliverer{Backoff: time.Second}
+	var tests = []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{100, maxBackoff},
+	}
+	for _, test := range tests {
+		if got := d.backoff(test.attempts); got != test.expected {
+			t.Errorf("backoff(%d): expected %s, got %s", test.attempts, test.expected, got)
+		}
+	}
+}